@@ -0,0 +1,201 @@
+package plugger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	chimw "github.com/go-chi/chi/middleware"
+)
+
+// AccessLogFormat is a format of access log records
+type AccessLogFormat int
+
+const (
+	// AccessLogCommon is the Apache Common Log Format
+	AccessLogCommon AccessLogFormat = iota
+	// AccessLogCombined is the Apache Combined Log Format,
+	// that adds referer and user agent to the Common one
+	AccessLogCombined
+	// AccessLogJSON writes a JSON object per line with
+	// the operation details and the request latency
+	AccessLogJSON
+)
+
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLogOption configures the access log
+type AccessLogOption func(*accessLog)
+
+// AccessLogSkip disables the access log for the operations
+func AccessLogSkip(operationIDs ...string) AccessLogOption {
+	return func(al *accessLog) {
+		for _, id := range operationIDs {
+			al.skip[id] = true
+		}
+	}
+}
+
+// AccessLogSampling logs only a part of successful requests
+//
+// rate is a share of requests to log from 0 to 1.
+// Requests that end with a 5xx status are always logged.
+func AccessLogSampling(rate float64) AccessLogOption {
+	return func(al *accessLog) {
+		al.rate = rate
+	}
+}
+
+// WithAccessLog writes a record to w for every request served by the plug
+//
+// Records contain the remote address, the method, the path, the status,
+// the response size and, in the JSON format, the path template,
+// the operationId and the request latency.
+func WithAccessLog(w io.Writer, format AccessLogFormat, opts ...AccessLogOption) Option {
+	return newOptionRouter(func(p *Plug) error {
+		if isNil(w) {
+			return errors.New("plugger: access log writer is nil")
+		}
+		al := &accessLog{
+			w:      w,
+			format: format,
+			rate:   1,
+			skip:   make(map[string]bool),
+		}
+		for _, opt := range opts {
+			opt(al)
+		}
+
+		if format < AccessLogCommon || format > AccessLogJSON {
+			return fmt.Errorf("plugger: unknown access log format %d", format)
+		}
+		if al.rate < 0 || al.rate > 1 {
+			return fmt.Errorf("plugger: access log sampling rate %v is out of [0, 1]", al.rate)
+		}
+
+		p.r.Use(al.middleware)
+		return nil
+	})
+}
+
+// accessLog writes access log records
+type accessLog struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format AccessLogFormat
+	rate   float64
+	skip   map[string]bool
+}
+
+// accessRecord is a single served request
+type accessRecord struct {
+	Time         time.Time `json:"time"`
+	RemoteAddr   string    `json:"remote_addr"`
+	Method       string    `json:"method"`
+	URI          string    `json:"uri"`
+	Proto        string    `json:"proto"`
+	PathTemplate string    `json:"path_template,omitempty"`
+	OperationID  string    `json:"operation_id,omitempty"`
+	Status       int       `json:"status"`
+	Bytes        int       `json:"bytes"`
+	LatencyMs    float64   `json:"latency_ms"`
+	Referer      string    `json:"referer,omitempty"`
+	UserAgent    string    `json:"user_agent,omitempty"`
+}
+
+func (al *accessLog) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op, _ := OperationFrom(r.Context())
+		if op != nil && al.skip[op.ID] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			// nothing was written, net/http responds with 200
+			status = http.StatusOK
+		}
+		if status < http.StatusInternalServerError && al.rate < 1 && rand.Float64() >= al.rate {
+			return
+		}
+
+		rec := accessRecord{
+			Time:       start,
+			RemoteAddr: remoteHost(r),
+			Method:     r.Method,
+			URI:        r.RequestURI,
+			Proto:      r.Proto,
+			Status:     status,
+			Bytes:      ww.BytesWritten(),
+			LatencyMs:  float64(time.Since(start)) / float64(time.Millisecond),
+			Referer:    r.Referer(),
+			UserAgent:  r.UserAgent(),
+		}
+		if rec.URI == "" {
+			rec.URI = r.URL.RequestURI()
+		}
+		if op != nil {
+			rec.PathTemplate = op.PathPattern
+			rec.OperationID = op.ID
+		}
+
+		al.write(rec)
+	})
+}
+
+func (al *accessLog) write(rec accessRecord) {
+	var buf bytes.Buffer
+
+	switch al.format {
+	case AccessLogJSON:
+		json.NewEncoder(&buf).Encode(rec)
+	default:
+		fmt.Fprintf(&buf, `%s - - [%s] "%s %s %s" %d %s`,
+			rec.RemoteAddr, rec.Time.Format(clfTimeFormat),
+			rec.Method, rec.URI, rec.Proto, rec.Status, clfBytes(rec.Bytes))
+		if al.format == AccessLogCombined {
+			fmt.Fprintf(&buf, ` "%s" "%s"`, dashIfEmpty(rec.Referer), dashIfEmpty(rec.UserAgent))
+		}
+		buf.WriteByte('\n')
+	}
+
+	al.mu.Lock()
+	al.w.Write(buf.Bytes())
+	al.mu.Unlock()
+}
+
+// clfBytes formats the response size, "-" is used for empty responses
+func clfBytes(n int) string {
+	if n == 0 {
+		return "-"
+	}
+	return fmt.Sprint(n)
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// remoteHost returns the client address without a port
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil || host == "" {
+		return dashIfEmpty(r.RemoteAddr)
+	}
+	return host
+}
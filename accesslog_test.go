@@ -0,0 +1,50 @@
+package plugger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ilyakaznacheev/go-plugger"
+)
+
+func TestWithAccessLog(t *testing.T) {
+	api := newGreetingAPI(t)
+	configureAPI(api)
+
+	var buf bytes.Buffer
+	p, err := plugger.NewAPIPlug(api, plugger.WithAccessLog(&buf, plugger.AccessLogJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hello?name=Bob", nil))
+
+	var rec struct {
+		Status      int    `json:"status"`
+		OperationID string `json:"operation_id"`
+		URI         string `json:"uri"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("can't decode the record %q: %v", buf.String(), err)
+	}
+	if rec.Status != http.StatusOK || rec.OperationID != "getGreeting" || rec.URI != "/hello?name=Bob" {
+		t.Errorf("record = %+v", rec)
+	}
+}
+
+func TestWithAccessLogErrors(t *testing.T) {
+	var nilBuf *bytes.Buffer
+	for name, opt := range map[string]plugger.Option{
+		"nil writer":       plugger.WithAccessLog(nil, plugger.AccessLogCommon),
+		"nil typed writer": plugger.WithAccessLog(nilBuf, plugger.AccessLogCommon),
+		"format":           plugger.WithAccessLog(ioutil.Discard, plugger.AccessLogFormat(42)),
+		"sampling":         plugger.WithAccessLog(ioutil.Discard, plugger.AccessLogCommon, plugger.AccessLogSampling(2)),
+	} {
+		if _, err := plugger.NewAPIPlug(newGreetingAPI(t), opt); err == nil {
+			t.Errorf("%s: NewAPIPlug() = nil error, want a config error", name)
+		}
+	}
+}
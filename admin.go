@@ -0,0 +1,185 @@
+package plugger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// defaultAdminTimeout is used to shut down the admin listener
+// if the server has no GracefulTimeout
+const defaultAdminTimeout = 15 * time.Second
+
+// ErrNoAdmin is used when an endpoint is attached to the admin listener,
+// but the plug has none
+var ErrNoAdmin = errors.New("plugger: plug has no admin listener, see WithAdminListener")
+
+// WithAdminListener serves operational endpoints on a separate listener
+//
+// network is "tcp" or "unix", and addr is the address to listen on,
+// e.g. "127.0.0.1:9090" or "/run/app/admin.sock".
+// Endpoints are attached to it with the OnAdmin endpoint option,
+// and custom routes can be added to AdminRouter.
+//
+// The admin listener is opened when the plug is served, and is shut down
+// after the server has stopped, so the endpoints stay available
+// while the server drains. The server GracefulTimeout applies to it as well.
+func WithAdminListener(network, addr string) Option {
+	return newOptionPlug(func(p *Plug) error {
+		if p.s == nil {
+			return fmt.Errorf("%w: admin listener can't be served", ErrNoServer)
+		}
+		switch network {
+		case "tcp", "tcp4", "tcp6", "unix":
+		default:
+			return fmt.Errorf("plugger: unsupported admin listener network %q", network)
+		}
+		p.admin = &adminServer{
+			network: network,
+			addr:    addr,
+			r:       chi.NewRouter(),
+		}
+		return nil
+	})
+}
+
+// OnAdmin serves the endpoint on the admin listener instead of the API listeners
+func OnAdmin() EndpointOption {
+	return func(cfg *endpointConfig) error {
+		cfg.admin = true
+		return nil
+	}
+}
+
+// WithPprof serves the net/http/pprof profiles under the path prefix,
+// e.g. "/debug/pprof"
+//
+// Profiles expose internals of the process, so serve them
+// on the admin listener with OnAdmin.
+func WithPprof(prefix string, opts ...EndpointOption) Option {
+	return newOptionRouter(func(p *Plug) error {
+		prefix = strings.TrimSuffix(prefix, "/")
+		return p.addEndpoint(prefix+"/*", pprofHandler(prefix), opts)
+	})
+}
+
+// AdminRouter returns the router of the admin listener,
+// or nil if the plug has none
+func (p *Plug) AdminRouter() chi.Router {
+	if p.admin == nil {
+		return nil
+	}
+	return p.admin.r
+}
+
+// AdminAddr returns the address the admin listener is bound to,
+// or nil if it isn't listening
+func (p *Plug) AdminAddr() net.Addr {
+	if p.admin == nil {
+		return nil
+	}
+	return p.admin.Addr()
+}
+
+// pprofHandler serves the profiles under the prefix.
+// The pprof index resolves profiles by the /debug/pprof/ path,
+// so the prefix is replaced with it.
+func pprofHandler(prefix string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, _ := trimPathPrefix(r.URL.Path, prefix)
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = "/debug/pprof" + rest
+		r2.URL.RawPath = ""
+		mux.ServeHTTP(w, r2)
+	})
+}
+
+// adminServer is the admin listener
+type adminServer struct {
+	network string
+	addr    string
+	r       chi.Router
+	// inherited is the listener passed by another process
+	inherited net.Listener
+
+	mu  sync.Mutex
+	l   net.Listener
+	srv *http.Server
+}
+
+// listen opens the admin listener and serves it in background
+func (a *adminServer) listen(logger Logger, wrap func(net.Listener) net.Listener) (net.Listener, error) {
+	l := a.inherited
+	if l == nil {
+		var err error
+		if l, err = net.Listen(a.network, a.addr); err != nil {
+			return nil, fmt.Errorf("plugger: admin listener: %w", err)
+		}
+	}
+	srv := &http.Server{Handler: a.r}
+
+	a.mu.Lock()
+	a.l, a.srv = l, srv
+	a.mu.Unlock()
+
+	logger.Info("serving admin endpoints", "address", l.Addr().String())
+	go func() {
+		if err := srv.Serve(wrap(l)); err != nil && err != http.ErrServerClosed {
+			logger.Error("admin listener failed", "error", err)
+		}
+	}()
+	return l, nil
+}
+
+// shutdown gracefully stops the admin listener
+func (a *adminServer) shutdown(timeout time.Duration, logger Logger) {
+	a.mu.Lock()
+	srv := a.srv
+	a.mu.Unlock()
+	if srv == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Error("admin listener shutdown failed", "error", err)
+	}
+}
+
+// Addr returns the bound address
+func (a *adminServer) Addr() net.Addr {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.l == nil {
+		return nil
+	}
+	return a.l.Addr()
+}
+
+// gracefulTimeout returns the server GracefulTimeout
+func (p *Plug) gracefulTimeout() time.Duration {
+	if d, ok := getDynParam(p.sv, "GracefulTimeout").(time.Duration); ok && d > 0 {
+		return d
+	}
+	return defaultAdminTimeout
+}
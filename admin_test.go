@@ -0,0 +1,114 @@
+package plugger_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ilyakaznacheev/go-plugger"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi"
+)
+
+// getStatus requests the url on a new connection
+func getStatus(url string) (int, error) {
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}, Timeout: 5 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestWithPprof(t *testing.T) {
+	api := newGreetingAPI(t)
+	configureAPI(api)
+	p, err := plugger.NewAPIPlug(api, plugger.WithPprof("/debug/pprof"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine") {
+		t.Errorf("GET /debug/pprof/ = %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestOnAdmin(t *testing.T) {
+	_, err := plugger.New(restapi.NewServer(nil), newGreetingAPI(t), plugger.WithPprof("/debug/pprof", plugger.OnAdmin()))
+	if !configErrorIs(err, plugger.ErrNoAdmin) {
+		t.Errorf("New() = %v, want %v", err, plugger.ErrNoAdmin)
+	}
+
+	api := newGreetingAPI(t)
+	configureAPI(api)
+	p, err := plugger.New(restapi.NewServer(nil), api,
+		plugger.WithHost("127.0.0.1"), plugger.WithPort(0),
+		plugger.WithoutSignalHandling(),
+		plugger.WithAdminListener("tcp", "127.0.0.1:0"),
+		plugger.WithPprof("/debug/pprof", plugger.OnAdmin()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	servePlug(t, p)
+
+	addrs := p.Addrs()
+	if addrs[plugger.AdminScheme].String() != p.AdminAddr().String() {
+		t.Errorf("Addrs() = %v, want the admin address %v", addrs, p.AdminAddr())
+	}
+	for url, want := range map[string]int{
+		"http://" + p.AdminAddr().String() + "/debug/pprof/": http.StatusOK,
+		"http://" + addrs["http"].String() + "/debug/pprof/": http.StatusNotFound,
+		"http://" + addrs["http"].String() + "/hello":        http.StatusOK,
+		"http://" + p.AdminAddr().String() + "/hello":        http.StatusNotFound,
+	} {
+		if code, err := getStatus(url); err != nil || code != want {
+			t.Errorf("GET %s = %d %v, want %d", url, code, err, want)
+		}
+	}
+}
+
+func TestAdminListenerDrain(t *testing.T) {
+	p, entered, release := slowPlug(t, 10*time.Second, plugger.WithAdminListener("tcp", "127.0.0.1:0"))
+	p.AdminRouter().Get("/ping", func(w http.ResponseWriter, r *http.Request) {})
+
+	errc := make(chan error, 1)
+	go func() { errc <- p.Serve() }()
+	<-p.Ready()
+	apiAddr, adminURL := p.Addrs()["http"].String(), "http://"+p.AdminAddr().String()+"/ping"
+	callSlow(t, p, entered)
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- p.Shutdown(context.Background()) }()
+
+	// the API listener is closed while the request is served
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		conn, err := net.Dial("tcp", apiAddr)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("API listener is open after Shutdown")
+		}
+	}
+	if code, err := getStatus(adminURL); err != nil || code != http.StatusOK {
+		t.Errorf("GET %s = %d %v while the server drains, want 200", adminURL, code, err)
+	}
+
+	close(release)
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown() = %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("Serve() = %v", err)
+	}
+	if _, err := getStatus(adminURL); err == nil {
+		t.Errorf("GET %s succeeds after the server has stopped", adminURL)
+	}
+}
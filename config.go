@@ -0,0 +1,657 @@
+package plugger
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/docker/go-units"
+	"github.com/jessevdk/go-flags"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the plug configuration that can be loaded from files,
+// environment variables and command-line flags, see LoadConfig
+//
+// Every field maps onto a plug option, the options are returned by Options.
+// Zero values are not applied, so the generated server keeps its own values.
+type Config struct {
+	Server    ServerConfig    `yaml:"server" json:"server" toml:"server"`
+	TLS       TLSConfig       `yaml:"tls" json:"tls" toml:"tls"`
+	API       APIConfig       `yaml:"api" json:"api" toml:"api"`
+	Lifecycle LifecycleConfig `yaml:"lifecycle" json:"lifecycle" toml:"lifecycle"`
+	Admin     AdminConfig     `yaml:"admin" json:"admin" toml:"admin"`
+	Endpoints EndpointsConfig `yaml:"endpoints" json:"endpoints" toml:"endpoints"`
+	AccessLog AccessLogConfig `yaml:"access_log" json:"access_log" toml:"access_log"`
+
+	// accessLog is the access log file opened by Options
+	accessLog *os.File
+}
+
+// ServerConfig configures listeners of the generated server
+type ServerConfig struct {
+	Host            string   `yaml:"host" json:"host" toml:"host" flag:"host"`
+	Port            int      `yaml:"port" json:"port" toml:"port" flag:"port"`
+	Listeners       []string `yaml:"listeners" json:"listeners" toml:"listeners" flag:"scheme"`
+	SocketPath      string   `yaml:"socket_path" json:"socket_path" toml:"socket_path" flag:"socket-path"`
+	ListenLimit     int      `yaml:"listen_limit" json:"listen_limit" toml:"listen_limit" flag:"listen-limit"`
+	MaxHeaderSize   ByteSize `yaml:"max_header_size" json:"max_header_size" toml:"max_header_size" flag:"max-header-size"`
+	KeepAlive       Duration `yaml:"keep_alive" json:"keep_alive" toml:"keep_alive" flag:"keep-alive"`
+	ReadTimeout     Duration `yaml:"read_timeout" json:"read_timeout" toml:"read_timeout" flag:"read-timeout"`
+	WriteTimeout    Duration `yaml:"write_timeout" json:"write_timeout" toml:"write_timeout" flag:"write-timeout"`
+	CleanupTimeout  Duration `yaml:"cleanup_timeout" json:"cleanup_timeout" toml:"cleanup_timeout" flag:"cleanup-timeout"`
+	GracefulTimeout Duration `yaml:"graceful_timeout" json:"graceful_timeout" toml:"graceful_timeout" flag:"graceful-timeout"`
+	Systemd         bool     `yaml:"systemd" json:"systemd" toml:"systemd"`
+}
+
+// TLSConfig configures the https listener of the generated server
+type TLSConfig struct {
+	Host          string   `yaml:"host" json:"host" toml:"host" flag:"tls-host"`
+	Port          int      `yaml:"port" json:"port" toml:"port" flag:"tls-port"`
+	Certificate   string   `yaml:"certificate" json:"certificate" toml:"certificate" flag:"tls-certificate"`
+	Key           string   `yaml:"key" json:"key" toml:"key" flag:"tls-key" secret:"true"`
+	CACertificate string   `yaml:"ca_certificate" json:"ca_certificate" toml:"ca_certificate" flag:"tls-ca"`
+	ListenLimit   int      `yaml:"listen_limit" json:"listen_limit" toml:"listen_limit" flag:"tls-listen-limit"`
+	KeepAlive     Duration `yaml:"keep_alive" json:"keep_alive" toml:"keep_alive" flag:"tls-keep-alive"`
+	ReadTimeout   Duration `yaml:"read_timeout" json:"read_timeout" toml:"read_timeout" flag:"tls-read-timeout"`
+	WriteTimeout  Duration `yaml:"write_timeout" json:"write_timeout" toml:"write_timeout" flag:"tls-write-timeout"`
+}
+
+// APIConfig configures the generated API
+type APIConfig struct {
+	BasePath       string `yaml:"base_path" json:"base_path" toml:"base_path"`
+	Configure      bool   `yaml:"configure" json:"configure" toml:"configure"`
+	Defaults       bool   `yaml:"defaults" json:"defaults" toml:"defaults"`
+	StrictHandlers bool   `yaml:"strict_handlers" json:"strict_handlers" toml:"strict_handlers"`
+	MockResponses  bool   `yaml:"mock_responses" json:"mock_responses" toml:"mock_responses"`
+}
+
+// LifecycleConfig configures signal handling and shutdown
+type LifecycleConfig struct {
+	// Signals are signal names, e.g. SIGTERM
+	Signals               []string `yaml:"signals" json:"signals" toml:"signals"`
+	WithoutSignalHandling bool     `yaml:"without_signal_handling" json:"without_signal_handling" toml:"without_signal_handling"`
+	WithoutFatalExit      bool     `yaml:"without_fatal_exit" json:"without_fatal_exit" toml:"without_fatal_exit"`
+	ShutdownDelay         Duration `yaml:"shutdown_delay" json:"shutdown_delay" toml:"shutdown_delay"`
+	RestartSignal         string   `yaml:"restart_signal" json:"restart_signal" toml:"restart_signal"`
+}
+
+// AdminConfig configures the admin listener, it is opened if Address is set
+type AdminConfig struct {
+	Network string `yaml:"network" json:"network" toml:"network"`
+	Address string `yaml:"address" json:"address" toml:"address"`
+}
+
+// EndpointConfig configures an operational endpoint, it is served if Path is set
+type EndpointConfig struct {
+	Path      string   `yaml:"path" json:"path" toml:"path"`
+	Listeners []string `yaml:"listeners" json:"listeners" toml:"listeners"`
+	Admin     bool     `yaml:"admin" json:"admin" toml:"admin"`
+}
+
+// HealthConfig configures the health endpoints
+type HealthConfig struct {
+	Enabled       bool     `yaml:"enabled" json:"enabled" toml:"enabled"`
+	LivenessPath  string   `yaml:"liveness_path" json:"liveness_path" toml:"liveness_path"`
+	ReadinessPath string   `yaml:"readiness_path" json:"readiness_path" toml:"readiness_path"`
+	Timeout       Duration `yaml:"timeout" json:"timeout" toml:"timeout"`
+	Cache         Duration `yaml:"cache" json:"cache" toml:"cache"`
+	Listeners     []string `yaml:"listeners" json:"listeners" toml:"listeners"`
+	Admin         bool     `yaml:"admin" json:"admin" toml:"admin"`
+}
+
+// EndpointsConfig configures the operational endpoints
+type EndpointsConfig struct {
+	Spec    EndpointConfig `yaml:"spec" json:"spec" toml:"spec"`
+	DocsUI  EndpointConfig `yaml:"docs_ui" json:"docs_ui" toml:"docs_ui"`
+	Metrics EndpointConfig `yaml:"metrics" json:"metrics" toml:"metrics"`
+	Pprof   EndpointConfig `yaml:"pprof" json:"pprof" toml:"pprof"`
+	Health  HealthConfig   `yaml:"health" json:"health" toml:"health"`
+}
+
+// AccessLogConfig configures the access log, it is written if Output is set
+type AccessLogConfig struct {
+	// Output is stdout, stderr or a file path
+	Output string `yaml:"output" json:"output" toml:"output"`
+	// Format is common, combined or json
+	Format   string   `yaml:"format" json:"format" toml:"format"`
+	Skip     []string `yaml:"skip" json:"skip" toml:"skip"`
+	Sampling float64  `yaml:"sampling" json:"sampling" toml:"sampling"`
+}
+
+// Duration is a time.Duration written as a string, e.g. "30s"
+type Duration time.Duration
+
+// UnmarshalText parses the duration
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText formats the duration
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// ByteSize is a size in bytes, written as a number or a string, e.g. "1MiB"
+type ByteSize int
+
+// UnmarshalText parses the size
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	v, err := units.RAMInBytes(string(text))
+	if err != nil {
+		return err
+	}
+	*b = ByteSize(v)
+	return nil
+}
+
+// UnmarshalJSON parses the size from a number or a string
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return b.UnmarshalText([]byte(s))
+	}
+	var n int
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*b = ByteSize(n)
+	return nil
+}
+
+// MarshalText formats the size in the largest unit it is a multiple of
+func (b ByteSize) MarshalText() ([]byte, error) {
+	for _, u := range []struct {
+		size   int
+		suffix string
+	}{{units.GiB, "GiB"}, {units.MiB, "MiB"}, {units.KiB, "KiB"}} {
+		if b != 0 && int(b)%u.size == 0 {
+			return []byte(strconv.Itoa(int(b)/u.size) + u.suffix), nil
+		}
+	}
+	return []byte(strconv.Itoa(int(b))), nil
+}
+
+// DefaultConfig returns the configuration with the generated server defaults
+func DefaultConfig() *Config {
+	return &Config{
+		Server: ServerConfig{
+			MaxHeaderSize:   ByteSize(units.MiB),
+			KeepAlive:       Duration(3 * time.Minute),
+			ReadTimeout:     Duration(30 * time.Second),
+			WriteTimeout:    Duration(60 * time.Second),
+			CleanupTimeout:  Duration(10 * time.Second),
+			GracefulTimeout: Duration(15 * time.Second),
+		},
+		Admin: AdminConfig{
+			Network: "tcp",
+		},
+	}
+}
+
+// ConfigOption tells LoadConfig where to read the configuration from
+type ConfigOption func(*configLoader) error
+
+type configLoader struct {
+	file   string
+	prefix string
+	parser *flags.Parser
+}
+
+// ConfigFile reads the configuration file
+//
+// The format is chosen by the extension: .yaml or .yml, .json and .toml.
+// Unknown keys are reported as errors.
+func ConfigFile(path string) ConfigOption {
+	return func(l *configLoader) error {
+		l.file = path
+		return nil
+	}
+}
+
+// ConfigEnv reads environment variables with the prefix
+//
+// A variable is named after the field path, e.g. with the prefix APP
+// the server port is set by APP_SERVER_PORT, and the metrics path by
+// APP_ENDPOINTS_METRICS_PATH. Lists are separated by commas.
+func ConfigEnv(prefix string) ConfigOption {
+	return func(l *configLoader) error {
+		if prefix == "" {
+			return errors.New("plugger: environment prefix is empty")
+		}
+		l.prefix = strings.ToUpper(strings.TrimSuffix(prefix, "_")) + "_"
+		return nil
+	}
+}
+
+// ConfigFlags takes the server flags from the parsed command line
+//
+// Pass the go-flags parser the generated server is registered with,
+// after the command line is parsed. Flags set explicitly take precedence
+// over the file and the environment. Flag defaults and the environment
+// variables of the generated server are used as defaults and environment.
+func ConfigFlags(parser *flags.Parser) ConfigOption {
+	return func(l *configLoader) error {
+		if parser == nil {
+			return errors.New("plugger: flag parser is nil")
+		}
+		l.parser = parser
+		return nil
+	}
+}
+
+// LoadConfig loads the configuration
+//
+// Sources override each other in this order:
+// defaults, the file, environment variables and command-line flags.
+// Options passed to New after Config.Options override all of them.
+func LoadConfig(opts ...ConfigOption) (*Config, error) {
+	l := &configLoader{}
+	for _, opt := range opts {
+		if err := opt(l); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg := DefaultConfig()
+	fields := configFields(cfg)
+
+	// defaults
+	if err := l.applyFlags(fields, func(o *flags.Option) bool {
+		return o.IsSetDefault() && !envIsSet(o)
+	}); err != nil {
+		return nil, err
+	}
+
+	// file
+	if l.file != "" {
+		if err := readConfigFile(l.file, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// environment
+	if err := l.applyFlags(fields, func(o *flags.Option) bool {
+		return o.IsSetDefault() && envIsSet(o)
+	}); err != nil {
+		return nil, err
+	}
+	if l.prefix != "" {
+		for _, f := range fields {
+			name := l.prefix + f.env
+			if v, ok := os.LookupEnv(name); ok {
+				if err := setConfigString(f.v, v); err != nil {
+					return nil, fmt.Errorf("plugger: %s: %w", name, err)
+				}
+			}
+		}
+	}
+
+	// flags
+	if err := l.applyFlags(fields, func(o *flags.Option) bool {
+		return o.IsSet() && !o.IsSetDefault()
+	}); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// applyFlags sets the fields from the matching flags
+func (l *configLoader) applyFlags(fields []configField, match func(*flags.Option) bool) error {
+	if l.parser == nil {
+		return nil
+	}
+	for _, f := range fields {
+		if f.flag == "" {
+			continue
+		}
+		o := l.parser.FindOptionByLongName(f.flag)
+		if o == nil || !match(o) {
+			continue
+		}
+		v := reflect.ValueOf(o.Value())
+		if !v.Type().ConvertibleTo(f.v.Type()) {
+			return fmt.Errorf("plugger: flag --%s of type %s doesn't match the configuration", f.flag, v.Type())
+		}
+		f.v.Set(v.Convert(f.v.Type()))
+	}
+	return nil
+}
+
+func envIsSet(o *flags.Option) bool {
+	if o.EnvDefaultKey == "" {
+		return false
+	}
+	_, ok := os.LookupEnv(o.EnvDefaultKey)
+	return ok
+}
+
+func readConfigFile(path string, cfg *Config) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("plugger: config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, cfg)
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(cfg)
+	case ".toml":
+		var md toml.MetaData
+		md, err = toml.Decode(string(data), cfg)
+		if undecoded := md.Undecoded(); err == nil && len(undecoded) > 0 {
+			err = fmt.Errorf("unknown keys %v", undecoded)
+		}
+	default:
+		return fmt.Errorf("plugger: config file %s: unsupported format %q", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("plugger: config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// configField is a configuration value
+type configField struct {
+	v      reflect.Value
+	env    string
+	flag   string
+	secret bool
+}
+
+// configFields returns the settable values of the configuration
+func configFields(cfg *Config) []configField {
+	var fields []configField
+
+	var walk func(v reflect.Value, env string)
+	walk = func(v reflect.Value, env string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.PkgPath != "" {
+				continue
+			}
+			name := strings.ToUpper(strings.Split(sf.Tag.Get("yaml"), ",")[0])
+			if env != "" {
+				name = env + "_" + name
+			}
+			if sf.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), name)
+				continue
+			}
+			fields = append(fields, configField{
+				v:      v.Field(i),
+				env:    name,
+				flag:   sf.Tag.Get("flag"),
+				secret: sf.Tag.Get("secret") == "true",
+			})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "")
+	return fields
+}
+
+// setConfigString sets the value from its string form
+func setConfigString(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// redactedValue replaces secrets in the dump
+const redactedValue = "<redacted>"
+
+// Dump writes the configuration as YAML with the secrets redacted
+func (c *Config) Dump(w io.Writer) error {
+	redacted := *c
+	for _, f := range configFields(&redacted) {
+		if f.secret && f.v.String() != "" {
+			f.v.SetString(redactedValue)
+		}
+	}
+
+	data, err := yaml.Marshal(&redacted)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Options returns the plug options set by the configuration
+//
+// If the access log is written to a file, Options opens it once
+// and the configuration owns it, call Close after the plug is stopped
+// or New has failed.
+func (c *Config) Options() []Option {
+	var opts []Option
+	add := func(set bool, opt Option) {
+		if set {
+			opts = append(opts, opt)
+		}
+	}
+
+	s := c.Server
+	add(s.Host != "", WithHost(s.Host))
+	add(s.Port != 0, WithPort(s.Port))
+	add(len(s.Listeners) > 0, WithEnabledListeners(s.Listeners))
+	add(s.SocketPath != "", WithSocketPath(s.SocketPath))
+	add(s.ListenLimit != 0, WithListenLimit(s.ListenLimit))
+	add(s.MaxHeaderSize != 0, WithMaxHeaderSize(int(s.MaxHeaderSize)))
+	add(s.KeepAlive != 0, WithKeepAlive(time.Duration(s.KeepAlive)))
+	add(s.ReadTimeout != 0, WithReadTimeout(time.Duration(s.ReadTimeout)))
+	add(s.WriteTimeout != 0, WithWriteTimeout(time.Duration(s.WriteTimeout)))
+	add(s.CleanupTimeout != 0, WithCleanupTimeout(time.Duration(s.CleanupTimeout)))
+	add(s.GracefulTimeout != 0, WithGracefulTimeout(time.Duration(s.GracefulTimeout)))
+	add(s.Systemd, WithSystemd())
+
+	t := c.TLS
+	add(t.Host != "", WithTLSHost(t.Host))
+	add(t.Port != 0, WithTLSPort(t.Port))
+	add(t.Certificate != "", WithTLSCertificate(t.Certificate))
+	add(t.Key != "", WithTLSCertificateKey(t.Key))
+	add(t.CACertificate != "", WithTLSCACertificate(t.CACertificate))
+	add(t.ListenLimit != 0, WithTLSListenLimit(t.ListenLimit))
+	add(t.KeepAlive != 0, WithTLSKeepAlive(time.Duration(t.KeepAlive)))
+	add(t.ReadTimeout != 0, WithTLSReadTimeout(time.Duration(t.ReadTimeout)))
+	add(t.WriteTimeout != 0, WithTLSWriteTimeout(time.Duration(t.WriteTimeout)))
+
+	a := c.API
+	add(a.BasePath != "", WithBasePath(a.BasePath))
+	add(a.Configure, WithConfiguredAPI())
+	add(a.Defaults, WithAPIDefaults())
+	add(a.StrictHandlers, WithStrictHandlers())
+	add(a.MockResponses, WithMockResponses())
+
+	l := c.Lifecycle
+	add(len(l.Signals) > 0, signalsOption(l.Signals, WithSignals))
+	add(l.WithoutSignalHandling, WithoutSignalHandling())
+	add(l.WithoutFatalExit, WithoutFatalExit())
+	add(l.ShutdownDelay != 0, WithShutdownDelay(time.Duration(l.ShutdownDelay)))
+	add(l.RestartSignal != "", signalsOption([]string{l.RestartSignal}, func(sigs ...os.Signal) Option {
+		return WithGracefulRestart(sigs[0])
+	}))
+
+	add(c.Admin.Address != "", WithAdminListener(c.Admin.Network, c.Admin.Address))
+
+	e := c.Endpoints
+	add(e.Spec.Path != "", WithSpecEndpoint(e.Spec.Path, e.Spec.options()...))
+	add(e.DocsUI.Path != "", WithDocsUI(e.DocsUI.Path, e.DocsUI.options()...))
+	add(e.Metrics.Path != "", WithMetrics(e.Metrics.Path, e.Metrics.options()...))
+	add(e.Pprof.Path != "", WithPprof(e.Pprof.Path, e.Pprof.options()...))
+	add(e.Health.Enabled, WithHealthChecks(e.Health.options()...))
+
+	add(c.AccessLog.Output != "", c.accessLogOption())
+
+	return opts
+}
+
+func (e EndpointConfig) options() []EndpointOption {
+	return endpointOptions(e.Listeners, e.Admin)
+}
+
+func (h HealthConfig) options() []HealthOption {
+	var opts []HealthOption
+	if h.LivenessPath != "" || h.ReadinessPath != "" {
+		liveness, readiness := h.LivenessPath, h.ReadinessPath
+		if liveness == "" {
+			liveness = defaultLivenessPath
+		}
+		if readiness == "" {
+			readiness = defaultReadinessPath
+		}
+		opts = append(opts, HealthPaths(liveness, readiness))
+	}
+	if h.Timeout != 0 {
+		opts = append(opts, HealthCheckTimeout(time.Duration(h.Timeout)))
+	}
+	if h.Cache != 0 {
+		opts = append(opts, HealthCheckCache(time.Duration(h.Cache)))
+	}
+	if eopts := endpointOptions(h.Listeners, h.Admin); len(eopts) > 0 {
+		opts = append(opts, HealthEndpoints(eopts...))
+	}
+	return opts
+}
+
+func endpointOptions(listeners []string, admin bool) []EndpointOption {
+	var opts []EndpointOption
+	if len(listeners) > 0 {
+		opts = append(opts, OnListeners(listeners...))
+	}
+	if admin {
+		opts = append(opts, OnAdmin())
+	}
+	return opts
+}
+
+// Close closes the access log file opened by Options
+func (c *Config) Close() error {
+	if c.accessLog == nil {
+		return nil
+	}
+	err := c.accessLog.Close()
+	c.accessLog = nil
+	return err
+}
+
+// accessLogOption opens the access log output
+func (c *Config) accessLogOption() Option {
+	al := c.AccessLog
+	formats := map[string]AccessLogFormat{
+		"":         AccessLogCommon,
+		"common":   AccessLogCommon,
+		"combined": AccessLogCombined,
+		"json":     AccessLogJSON,
+	}
+	format, ok := formats[strings.ToLower(al.Format)]
+	if !ok {
+		return newOptionRouter(func(*Plug) error {
+			return fmt.Errorf("plugger: unknown access log format %q", al.Format)
+		})
+	}
+
+	var opts []AccessLogOption
+	if len(al.Skip) > 0 {
+		opts = append(opts, AccessLogSkip(al.Skip...))
+	}
+	if al.Sampling != 0 {
+		opts = append(opts, AccessLogSampling(al.Sampling))
+	}
+
+	switch al.Output {
+	case "stdout":
+		return WithAccessLog(os.Stdout, format, opts...)
+	case "stderr":
+		return WithAccessLog(os.Stderr, format, opts...)
+	}
+	if c.accessLog == nil {
+		f, err := os.OpenFile(al.Output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return newOptionRouter(func(*Plug) error {
+				return fmt.Errorf("plugger: access log: %w", err)
+			})
+		}
+		c.accessLog = f
+	}
+	return WithAccessLog(c.accessLog, format, opts...)
+}
+
+// signalsOption creates the option with the named signals
+func signalsOption(names []string, opt func(...os.Signal) Option) Option {
+	sigs := make([]os.Signal, 0, len(names))
+	for _, name := range names {
+		sig, ok := signalByName(name)
+		if !ok {
+			return newOptionPlug(func(*Plug) error {
+				return fmt.Errorf("plugger: unknown signal %q", name)
+			})
+		}
+		sigs = append(sigs, sig)
+	}
+	return opt(sigs...)
+}
+
+// signalByName finds the signal by its name, e.g. SIGTERM or TERM
+func signalByName(name string) (os.Signal, bool) {
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig, ok := signalNames[name]
+	return sig, ok
+}
@@ -0,0 +1,217 @@
+package plugger_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/ilyakaznacheev/go-plugger"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi"
+)
+
+// writeConfig writes the configuration file to a temporary directory
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigFormats(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": "server:\n  port: 8081\n  read_timeout: 5s\n  max_header_size: 2MiB\n  listeners: [http]\ntls:\n  key: /secret.key\nendpoints:\n  metrics:\n    path: /metrics\n",
+		"config.json": `{"server":{"port":8081,"read_timeout":"5s","max_header_size":2097152,"listeners":["http"]},"tls":{"key":"/secret.key"},"endpoints":{"metrics":{"path":"/metrics"}}}`,
+		"config.toml": "[server]\nport = 8081\nread_timeout = \"5s\"\nmax_header_size = \"2MiB\"\nlisteners = [\"http\"]\n[tls]\nkey = \"/secret.key\"\n[endpoints.metrics]\npath = \"/metrics\"\n",
+	}
+	for name, content := range files {
+		cfg, err := plugger.LoadConfig(plugger.ConfigFile(writeConfig(t, name, content)))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if cfg.Server.Port != 8081 ||
+			time.Duration(cfg.Server.ReadTimeout) != 5*time.Second ||
+			cfg.Server.MaxHeaderSize != 2<<20 ||
+			strings.Join(cfg.Server.Listeners, ",") != "http" ||
+			cfg.TLS.Key != "/secret.key" ||
+			cfg.Endpoints.Metrics.Path != "/metrics" {
+			t.Errorf("%s: loaded %+v", name, cfg)
+		}
+	}
+
+	invalid := map[string]string{
+		"unknown.yaml": "server:\n  prot: 1\n",
+		"unknown.json": `{"srv":{}}`,
+		"unknown.toml": "[server]\nprot = 1\n",
+		"config.ini":   "",
+	}
+	for name, content := range invalid {
+		if _, err := plugger.LoadConfig(plugger.ConfigFile(writeConfig(t, name, content))); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	env := map[string]string{
+		"APP_SERVER_PORT":          "9000",
+		"APP_SERVER_WRITE_TIMEOUT": "7s",
+		"APP_SERVER_LISTENERS":     "http, unix",
+		"TLS_PORT":                 "9443", // read by the generated flags
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	parser := flags.NewParser(restapi.NewServer(nil), flags.Default)
+	if _, err := parser.ParseArgs([]string{"--read-timeout=3s", "--tls-key=/flag.key"}); err != nil {
+		t.Fatal(err)
+	}
+	file := writeConfig(t, "config.yml",
+		"server:\n  port: 8081\n  read_timeout: 5s\n  write_timeout: 6s\n  keep_alive: 1m\n  host: 0.0.0.0\ntls:\n  port: 1\n")
+
+	cfg, err := plugger.LoadConfig(plugger.ConfigFile(file), plugger.ConfigEnv("app"), plugger.ConfigFlags(parser))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := cfg.Server
+	if s.Port != 9000 {
+		t.Errorf("port = %d, want 9000 from the environment", s.Port)
+	}
+	if time.Duration(s.ReadTimeout) != 3*time.Second {
+		t.Errorf("read timeout = %v, want 3s from the flags", time.Duration(s.ReadTimeout))
+	}
+	if time.Duration(s.WriteTimeout) != 7*time.Second {
+		t.Errorf("write timeout = %v, want 7s from the environment", time.Duration(s.WriteTimeout))
+	}
+	if time.Duration(s.KeepAlive) != time.Minute || s.Host != "0.0.0.0" {
+		t.Errorf("keep alive = %v, host = %q, want the file values", time.Duration(s.KeepAlive), s.Host)
+	}
+	if got := strings.Join(s.Listeners, ","); got != "http,unix" {
+		t.Errorf("listeners = %q", got)
+	}
+	if cfg.TLS.Port != 9443 || cfg.TLS.Key != "/flag.key" {
+		t.Errorf("tls = %+v", cfg.TLS)
+	}
+
+	var dump strings.Builder
+	if err := cfg.Dump(&dump); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(dump.String(), "/flag.key") || !strings.Contains(dump.String(), "<redacted>") {
+		t.Errorf("secret is not redacted:\n%s", dump.String())
+	}
+	if cfg.TLS.Key != "/flag.key" {
+		t.Error("Dump has changed the configuration")
+	}
+}
+
+func TestConfigOptions(t *testing.T) {
+	accessLog := filepath.Join(t.TempDir(), "access.log")
+	file := writeConfig(t, "config.yaml", `
+server:
+  host: 127.0.0.1
+  port: 0
+  listeners: [http]
+  write_timeout: 1m
+api:
+  strict_handlers: true
+lifecycle:
+  shutdown_delay: 10ms
+admin:
+  address: 127.0.0.1:0
+endpoints:
+  metrics:
+    path: /metrics
+    admin: true
+  spec:
+    path: /swagger.json
+  health:
+    enabled: true
+    readiness_path: /ready
+    admin: true
+access_log:
+  output: `+accessLog+`
+  format: json
+`)
+	cfg, err := plugger.LoadConfig(plugger.ConfigFile(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cfg.Close()
+
+	api := newGreetingAPI(t)
+	configureAPI(api)
+	srv := restapi.NewServer(nil)
+	// options passed in code override the configuration
+	p, err := plugger.New(srv, api, append(cfg.Options(), plugger.WithWriteTimeout(time.Second))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.WriteTimeout != time.Second {
+		t.Errorf("write timeout = %v, want 1s", srv.WriteTimeout)
+	}
+
+	go p.Serve()
+	<-p.Ready()
+	addrs := p.Addrs()
+	for _, u := range []string{
+		"http://" + addrs["http"].String() + "/hello",
+		"http://" + addrs["http"].String() + "/swagger.json",
+		"http://" + addrs[plugger.AdminScheme].String() + "/metrics",
+		"http://" + addrs[plugger.AdminScheme].String() + "/ready",
+	} {
+		resp, err := http.Get(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s = %d", u, resp.StatusCode)
+		}
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+	records, err := ioutil.ReadFile(accessLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(records), `"operation_id":"getGreeting"`) {
+		t.Errorf("access log has no getGreeting record:\n%s", records)
+	}
+}
+
+func TestConfigOptionsInvalid(t *testing.T) {
+	cfg := &plugger.Config{
+		Lifecycle: plugger.LifecycleConfig{Signals: []string{"SIGNOPE"}},
+		AccessLog: plugger.AccessLogConfig{Output: filepath.Join(t.TempDir(), "access.log")},
+	}
+	// the access log file is owned by the configuration if New fails
+	defer cfg.Close()
+
+	if _, err := plugger.New(restapi.NewServer(nil), newGreetingAPI(t), cfg.Options()...); err == nil {
+		t.Error("no error for an unknown signal")
+	}
+
+	format := &plugger.Config{AccessLog: plugger.AccessLogConfig{Output: "stdout", Format: "xml"}}
+	if _, err := plugger.New(restapi.NewServer(nil), newGreetingAPI(t), format.Options()...); err == nil {
+		t.Error("no error for an unknown access log format")
+	}
+}
@@ -0,0 +1,165 @@
+package plugger
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/go-openapi/spec"
+	"gopkg.in/yaml.v2"
+)
+
+// media types the spec is served with
+const (
+	specJSONType = "application/json"
+	specYAMLType = "application/yaml"
+)
+
+// WithSpecEndpoint serves the API spec at path
+//
+// The spec is served as JSON, or as YAML if the request path ends
+// with .yaml or .yml, has the format=yaml query parameter
+// or accepts YAML only. The host, the basePath and the schemes
+// are rewritten to the ones the request was received with,
+// so the spec can be used to call the API right away.
+func WithSpecEndpoint(path string, opts ...EndpointOption) Option {
+	return newOptionRouter(func(p *Plug) error {
+		if _, err := specOf(p.apiv); err != nil {
+			return err
+		}
+		return p.addEndpoint(path, http.HandlerFunc(p.serveSpec), opts)
+	})
+}
+
+// WithDocsUI serves the Swagger UI documentation page at path
+//
+// Swagger UI is bundled with the package and served at path/swagger-ui-bundle.js
+// and path/swagger-ui.css, so the page doesn't load any external assets
+// and works offline. The spec is inlined into the page, with the host
+// and the basePath the request was received with, so the operations
+// can be tried out right away.
+func WithDocsUI(path string, opts ...EndpointOption) Option {
+	return newOptionRouter(func(p *Plug) error {
+		if _, err := specOf(p.apiv); err != nil {
+			return err
+		}
+		if err := p.addEndpoint(path, http.HandlerFunc(p.serveDocs), opts); err != nil {
+			return err
+		}
+		for _, name := range docsAssetNames {
+			if err := p.addEndpoint(strings.TrimSuffix(path, "/")+"/"+name, serveDocsAsset(name), opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (p *Plug) serveSpec(w http.ResponseWriter, r *http.Request) {
+	sw, err := p.requestSpec(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.MarshalIndent(sw, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	contentType := specJSONType
+
+	if wantsYAML(r) {
+		// JSON is YAML, the map slice keeps the keys order
+		var doc yaml.MapSlice
+		if err := yaml.Unmarshal(body, &doc); err == nil {
+			body, err = yaml.Marshal(doc)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			contentType = specYAMLType
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
+
+func (p *Plug) serveDocs(w http.ResponseWriter, r *http.Request) {
+	sw, err := p.requestSpec(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	specJSON, err := json.Marshal(sw)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	err = docsTemplate.Execute(&buf, struct {
+		Title  string
+		Assets string
+		Spec   template.JS
+	}{
+		Title:  docsTitle(sw),
+		Assets: docsAssetsBase(r),
+		// json.Marshal escapes HTML characters, so it is safe inside of a script
+		Spec: template.JS(specJSON),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// requestSpec returns the API spec with the host, the basePath and the schemes
+// the request was received with
+func (p *Plug) requestSpec(r *http.Request) (*spec.Swagger, error) {
+	doc, err := specOf(p.apiv)
+	if err != nil {
+		return nil, err
+	}
+
+	// the document is shared, so the fields are changed in a copy
+	sw := *doc.Spec()
+	sw.Host = r.Host
+	if p.basePath != "" {
+		sw.BasePath = p.basePath
+	}
+	if r.TLS != nil {
+		sw.Schemes = []string{schemeHTTPS}
+	} else {
+		sw.Schemes = []string{schemeHTTP}
+	}
+	return &sw, nil
+}
+
+// wantsYAML checks if the spec is requested as YAML
+func wantsYAML(r *http.Request) bool {
+	if strings.HasSuffix(r.URL.Path, ".yaml") || strings.HasSuffix(r.URL.Path, ".yml") {
+		return true
+	}
+	switch r.URL.Query().Get("format") {
+	case "yaml", "yml":
+		return true
+	case "json":
+		return false
+	}
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "yaml") && !strings.Contains(accept, "json")
+}
+
+func docsTitle(sw *spec.Swagger) string {
+	if sw.Info != nil && sw.Info.Title != "" {
+		return sw.Info.Title
+	}
+	return "API"
+}
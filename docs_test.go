@@ -0,0 +1,87 @@
+package plugger_test
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/ilyakaznacheev/go-plugger"
+)
+
+func TestWithSpecEndpoint(t *testing.T) {
+	p, err := plugger.NewAPIPlug(newGreetingAPI(t), plugger.WithBasePath("/v1"),
+		plugger.WithSpecEndpoint("/swagger.json"), plugger.WithSpecEndpoint("/swagger.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var spec struct {
+		Host     string   `json:"host" yaml:"host"`
+		BasePath string   `json:"basePath" yaml:"basePath"`
+		Schemes  []string `json:"schemes" yaml:"schemes"`
+	}
+	for path, unmarshal := range map[string]func([]byte, interface{}) error{
+		"/swagger.json": json.Unmarshal,
+		"/swagger.yaml": yaml.Unmarshal,
+	} {
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://api.test"+path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d", path, rec.Code)
+		}
+		if err := unmarshal(rec.Body.Bytes(), &spec); err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		if spec.Host != "api.test" || spec.BasePath != "/v1" || len(spec.Schemes) != 1 || spec.Schemes[0] != "http" {
+			t.Errorf("GET %s: spec = %+v, want the request host and the plug basePath", path, spec)
+		}
+	}
+}
+
+func TestWithDocsUI(t *testing.T) {
+	p, err := plugger.NewAPIPlug(newGreetingAPI(t), plugger.WithDocsUI("/docs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://api.test/docs", nil))
+	page := rec.Body.String()
+	for _, s := range []string{`href="docs/swagger-ui.css"`, `src="docs/swagger-ui-bundle.js"`, `"host":"api.test"`, `SwaggerUIBundle(`} {
+		if !strings.Contains(page, s) {
+			t.Errorf("the docs page doesn't contain %s", s)
+		}
+	}
+
+	for _, name := range []string{"swagger-ui-bundle.js", "swagger-ui.css"} {
+		req := httptest.NewRequest(http.MethodGet, "/docs/"+name, nil)
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, req)
+		plain := rec.Body.String()
+
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec = httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, req)
+		if rec.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("%s isn't compressed", name)
+		}
+		zr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		unzipped, err := ioutil.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(plain, "swagger-ui") || string(unzipped) != plain {
+			t.Errorf("%s isn't served", name)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package plugger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"embed"
+	"html/template"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// docsAssets are the gzipped Swagger UI files, see swagger-ui/NOTICE
+//
+//go:embed swagger-ui/swagger-ui-bundle.js.gz swagger-ui/swagger-ui.css.gz
+var docsAssets embed.FS
+
+// docsAssetNames are the Swagger UI files served next to the docs page
+var docsAssetNames = []string{"swagger-ui-bundle.js", "swagger-ui.css"}
+
+// docsTemplate is the Swagger UI page with the spec inlined
+var docsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.Assets}}swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{.Assets}}swagger-ui-bundle.js"></script>
+<script>
+window.ui = SwaggerUIBundle({
+  spec: {{.Spec}},
+  dom_id: "#swagger-ui",
+  deepLinking: true,
+  presets: [SwaggerUIBundle.presets.apis]
+});
+</script>
+</body>
+</html>
+`))
+
+// docsAssetsBase returns the URL of the assets relative to the docs page
+func docsAssetsBase(r *http.Request) string {
+	if strings.HasSuffix(r.URL.Path, "/") {
+		return "./"
+	}
+	return path.Base(r.URL.Path) + "/"
+}
+
+// serveDocsAsset serves a bundled Swagger UI file, compressed if the client accepts it
+func serveDocsAsset(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := docsAssets.ReadFile("swagger-ui/" + name + ".gz")
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		contentType := "text/css; charset=utf-8"
+		if strings.HasSuffix(name, ".js") {
+			contentType = "text/javascript; charset=utf-8"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		w.Header().Set("Vary", "Accept-Encoding")
+
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(data)
+			return
+		}
+
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		io.Copy(w, zr)
+	})
+}
@@ -0,0 +1,86 @@
+package plugger
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// setDynParam sets the value to the exported field key of the struct srvVal points to
+func setDynParam(srvVal reflect.Value, key string, value interface{}) error {
+	target := reflect.Indirect(srvVal)
+	if target.Kind() != reflect.Struct {
+		return &FieldError{Target: srvVal.Type().String(), Field: key, Err: ErrFieldNotFound}
+	}
+
+	field := target.FieldByName(key)
+	if !field.IsValid() {
+		return &FieldError{Target: target.Type().String(), Field: key, Err: ErrFieldNotFound}
+	}
+	if !field.CanSet() {
+		return &FieldError{Target: target.Type().String(), Field: key, Err: ErrFieldNotSettable}
+	}
+
+	rValue := reflect.ValueOf(value)
+	if !rValue.IsValid() {
+		// untyped nil resets the field
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if !rValue.Type().AssignableTo(field.Type()) {
+		return &FieldError{
+			Target: target.Type().String(),
+			Field:  key,
+			Err:    fmt.Errorf("%w: %s can't be used as %s", ErrFieldType, rValue.Type(), field.Type()),
+		}
+	}
+
+	field.Set(rValue)
+	return nil
+}
+
+// setDynDefault works as setDynParam, but skips fields
+// the target doesn't have, because generated types
+// contain only fields required by their spec
+func setDynDefault(srvVal reflect.Value, key string, value interface{}) error {
+	err := setDynParam(srvVal, key, value)
+	if errors.Is(err, ErrFieldNotFound) {
+		return nil
+	}
+	return err
+}
+
+// getDynParam returns a value of the exported field key of the struct srvVal points to
+func getDynParam(srvVal reflect.Value, key string) interface{} {
+	if rField := reflect.Indirect(srvVal).FieldByName(key); rField.IsValid() && rField.CanInterface() {
+		return rField.Interface()
+	}
+	return nil
+}
+
+// dynField returns a settable field key of the struct srvVal points to.
+// Unlike setDynParam and getDynParam, it works with unexported fields.
+// It returns an invalid value if there is no such field.
+func dynField(srvVal reflect.Value, key string) reflect.Value {
+	target := reflect.Indirect(srvVal)
+	if target.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+
+	field := target.FieldByName(key)
+	if !field.IsValid() || !field.CanAddr() {
+		return reflect.Value{}
+	}
+
+	// there is no other way to access generated unexported fields
+	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+}
+
+// resetDynField sets the field key of the struct srvVal points to
+// to its zero value
+func resetDynField(srvVal reflect.Value, key string) {
+	if field := dynField(srvVal, key); field.IsValid() {
+		field.Set(reflect.Zero(field.Type()))
+	}
+}
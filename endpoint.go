@@ -0,0 +1,109 @@
+package plugger
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// endpoint is an operational endpoint served next to the API
+type endpoint struct {
+	path    string
+	handler http.Handler
+	// admin endpoints are served on the admin listener
+	admin bool
+}
+
+// EndpointOption configures an operational endpoint,
+// such as the metrics or the spec one
+type EndpointOption func(*endpointConfig) error
+
+type endpointConfig struct {
+	schemes map[string]bool
+	admin   bool
+}
+
+// OnListeners serves the endpoint only on the listeners of the schemes,
+// one of "http", "https" or "unix"
+//
+// Requests received by other listeners get 404 Not Found.
+// By default the endpoint is served on all listeners.
+func OnListeners(schemes ...string) EndpointOption {
+	return func(cfg *endpointConfig) error {
+		if cfg.schemes == nil {
+			cfg.schemes = make(map[string]bool)
+		}
+		for _, scheme := range schemes {
+			switch scheme {
+			case schemeHTTP, schemeHTTPS, schemeUnix:
+				cfg.schemes[scheme] = true
+			default:
+				return fmt.Errorf("plugger: unknown listener scheme %q", scheme)
+			}
+		}
+		return nil
+	}
+}
+
+// addEndpoint registers the endpoint to be routed after the router middleware
+func (p *Plug) addEndpoint(path string, h http.Handler, opts []EndpointOption) error {
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("plugger: endpoint path %q must start with /", path)
+	}
+
+	var cfg endpointConfig
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return err
+		}
+	}
+	if cfg.admin && p.admin == nil {
+		return ErrNoAdmin
+	}
+
+	for _, e := range p.endpoints {
+		if e.path == path && e.admin == cfg.admin {
+			return fmt.Errorf("plugger: endpoint %q is already registered", path)
+		}
+	}
+
+	if cfg.schemes != nil {
+		h = onListeners(cfg.schemes, h)
+	}
+	p.endpoints = append(p.endpoints, endpoint{path: path, handler: h, admin: cfg.admin})
+	return nil
+}
+
+// routeEndpoints adds the endpoints to the plug and the admin routers
+func (p *Plug) routeEndpoints() {
+	for _, e := range p.endpoints {
+		if e.admin {
+			p.admin.r.Handle(e.path, e.handler)
+			continue
+		}
+		p.r.Handle(e.path, e.handler)
+	}
+}
+
+// onListeners responds with 404 to requests received by other listeners
+func onListeners(schemes map[string]bool, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !schemes[listenerScheme(r)] {
+			http.NotFound(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// listenerScheme tells which listener the request was received by
+func listenerScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return schemeHTTPS
+	}
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && addr.Network() == "unix" {
+		return schemeUnix
+	}
+	return schemeHTTP
+}
@@ -0,0 +1,86 @@
+package plugger
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrFieldNotFound is used when an option target has no such field
+	ErrFieldNotFound = errors.New("field not found")
+	// ErrFieldNotSettable is used when an option target field can't be set
+	ErrFieldNotSettable = errors.New("field can't be set")
+	// ErrFieldType is used when an option value doesn't fit the target field type
+	ErrFieldType = errors.New("incompatible field type")
+
+	// ErrNilServer is used when a nil server is passed to the plug
+	ErrNilServer = errors.New("server is nil")
+	// ErrNilAPI is used when a nil API is passed to the plug
+	ErrNilAPI = errors.New("api is nil")
+	// ErrNoSpec is used when the API has no spec document
+	ErrNoSpec = errors.New("api has no spec document")
+	// ErrUnknownOperation is used when the spec has no operation with such operationId
+	ErrUnknownOperation = errors.New("unknown operation")
+	// ErrUnknownTag is used when no operation in the spec has such tag
+	ErrUnknownTag = errors.New("unknown tag")
+
+	// ErrNoSetAPI is used when the server can't be bound to the API
+	ErrNoSetAPI = errors.New("server has no SetAPI method compatible with the api")
+)
+
+// FieldError describes a field of the server or the API
+// that an option failed to set
+type FieldError struct {
+	// Target is a type name of the option target
+	Target string
+	// Field is a name of the field
+	Field string
+	// Err is a reason of the failure
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s.%s: %v", e.Target, e.Field, e.Err)
+}
+
+// Unwrap returns the reason of the failure
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigError lists all problems found while setting up a plug
+type ConfigError struct {
+	Errors []error
+}
+
+func (e *ConfigError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return "plugger: invalid configuration: " + strings.Join(msgs, "; ")
+}
+
+// configErrors collects setup errors
+type configErrors []error
+
+func (ce *configErrors) add(err error) {
+	if err == nil {
+		return
+	}
+	// keep the list flat if an option reports several errors
+	if cfgErr, ok := err.(*ConfigError); ok {
+		*ce = append(*ce, cfgErr.Errors...)
+		return
+	}
+	*ce = append(*ce, err)
+}
+
+// err returns a *ConfigError if any error was collected
+func (ce configErrors) err() error {
+	if len(ce) == 0 {
+		return nil
+	}
+	return &ConfigError{Errors: ce}
+}
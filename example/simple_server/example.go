@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"github.com/go-openapi/loads"
@@ -40,7 +41,7 @@ func main() {
 	plug := plugger.NewPlug(srv, api,
 		plugger.WithHost("localhost"),
 		plugger.WithPort(8000))
-	defer plug.Shutdown()
+	defer plug.Shutdown(context.Background())
 
 	// run server
 	err = plug.Serve()
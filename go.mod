@@ -1,8 +1,9 @@
 module github.com/ilyakaznacheev/go-plugger
 
-go 1.13
+go 1.16
 
 require (
+	github.com/BurntSushi/toml v0.3.0
 	github.com/docker/go-units v0.4.0
 	github.com/go-chi/chi v4.1.1+incompatible
 	github.com/go-openapi/errors v0.19.2
@@ -11,6 +12,8 @@ require (
 	github.com/go-openapi/spec v0.19.3
 	github.com/go-openapi/strfmt v0.19.3
 	github.com/go-openapi/swag v0.19.5
+	github.com/go-openapi/validate v0.19.3
 	github.com/jessevdk/go-flags v1.4.0
 	golang.org/x/net v0.0.0-20190827160401-ba9fcec4b297
+	gopkg.in/yaml.v2 v2.2.4
 )
@@ -0,0 +1,155 @@
+package plugger
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-openapi/runtime/middleware"
+)
+
+// fields of the generated API that cache handlers
+// and routes built on the first API.Serve call
+var apiCacheFields = []string{"handlers", "context"}
+
+// apiBuild is a built go-swagger handler
+type apiBuild struct {
+	handler http.Handler
+	// ctx is the go-swagger context the handler routes requests with
+	ctx *middleware.Context
+}
+
+// current returns the go-swagger API handler.
+// The handler is built on the first call, so any API
+// changes made after the plug construction are respected.
+func (p *Plug) current() *apiBuild {
+	if b, ok := p.handler.Load().(*apiBuild); ok {
+		return b
+	}
+
+	p.buildMu.Lock()
+	defer p.buildMu.Unlock()
+
+	if b, ok := p.handler.Load().(*apiBuild); ok {
+		return b
+	}
+	return p.build()
+}
+
+// serveAPI serves requests with the current API handler
+func (p *Plug) serveAPI(w http.ResponseWriter, r *http.Request) {
+	b := p.current()
+
+	r, ok := p.apiRequest(r, b)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	b.handler.ServeHTTP(w, r)
+}
+
+// Rebuild builds the API handler again
+//
+// go-swagger caches operation handlers the first time the API is served,
+// so handlers or API fields assigned later are ignored.
+// Call Rebuild after such changes to pick them up.
+// The APIs are validated first, and the current handlers
+// stay in use if the validation fails.
+//
+// APIs mounted with MountAPI are rebuilt as well.
+func (p *Plug) Rebuild() error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+	p.rebuild()
+	return nil
+}
+
+// validate validates the main and the mounted APIs
+func (p *Plug) validate() error {
+	if err := p.api.Validate(); err != nil {
+		return err
+	}
+	for _, sub := range p.mounts {
+		if err := sub.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Plug) rebuild() {
+	p.buildMu.Lock()
+	p.build()
+	p.buildMu.Unlock()
+
+	for _, sub := range p.mounts {
+		sub.rebuild()
+	}
+}
+
+// build drops the API caches and creates a new handler
+func (p *Plug) build() *apiBuild {
+	for _, key := range apiCacheFields {
+		resetDynField(p.apiv, key)
+	}
+
+	restore := p.installMocks()
+	p.addOperationMiddleware()
+	if p.tracing {
+		recordServeErrors(p.apiv)
+	}
+
+	b := &apiBuild{
+		handler: p.api.Serve(p.routedBuilder),
+		ctx:     p.api.Context(),
+	}
+	restore()
+	p.handler.Store(b)
+	return b
+}
+
+// apiRequest maps a request under the plug base path to the spec basePath,
+// so go-swagger can route it. It returns false if the request is out of the base path.
+func (p *Plug) apiRequest(r *http.Request, b *apiBuild) (*http.Request, bool) {
+	if p.basePath == "" {
+		return r, true
+	}
+
+	prefix := strings.TrimSuffix(p.basePath, "/")
+	basePath := strings.TrimSuffix(b.ctx.BasePath(), "/")
+
+	rest, ok := trimPathPrefix(r.URL.Path, prefix)
+	if !ok {
+		return r, false
+	}
+
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = basePath + rest
+	if r.URL.RawPath != "" {
+		rawRest, _ := trimPathPrefix(r.URL.RawPath, prefix)
+		r2.URL.RawPath = basePath + rawRest
+	}
+	return r2, true
+}
+
+// trimPathPrefix removes the prefix if the path is equal to it
+// or continues it with a new segment
+func trimPathPrefix(path, prefix string) (string, bool) {
+	if !strings.HasPrefix(path, prefix) {
+		return "", false
+	}
+
+	rest := path[len(prefix):]
+	switch {
+	case rest == "":
+		return "/", true
+	case rest[0] == '/':
+		return rest, true
+	default:
+		return "", false
+	}
+}
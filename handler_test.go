@@ -0,0 +1,74 @@
+package plugger_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-openapi/runtime/middleware"
+
+	"github.com/ilyakaznacheev/go-plugger"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi/operations"
+)
+
+func TestRebuild(t *testing.T) {
+	var calls int
+	counter := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	api := newGreetingAPI(t)
+	configureAPI(api)
+	p, err := plugger.NewAPIPlug(api, plugger.WithOperationMiddleware("getGreeting", counter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := p.Handler()
+
+	get := func() string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hello", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET /hello = %d", rec.Code)
+		}
+		return rec.Body.String()
+	}
+
+	if got := get(); got != "Hello, World!" {
+		t.Fatalf("GET /hello = %q before the change", got)
+	}
+
+	api.GetGreetingHandler = operations.GetGreetingHandlerFunc(func(operations.GetGreetingParams) middleware.Responder {
+		return operations.NewGetGreetingOK().WithPayload("Bye!")
+	})
+	// go-swagger keeps the cached handler until the rebuild
+	if got := get(); got != "Hello, World!" {
+		t.Errorf("GET /hello = %q before Rebuild, want the cached handler", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := p.Rebuild(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := get(); got != "Bye!" {
+		t.Errorf("GET /hello = %q after Rebuild, want the new handler", got)
+	}
+	// the operation middleware isn't wrapped again on every rebuild
+	if calls != 3 {
+		t.Errorf("operation middleware is called %d times for 3 requests", calls)
+	}
+
+	// the current handler stays in use if the API is invalid
+	api.GetGreetingHandler = nil
+	if err := p.Rebuild(); err == nil {
+		t.Error("Rebuild() = nil for an API without a handler")
+	}
+	if got := get(); got != "Bye!" {
+		t.Errorf("GET /hello = %q after a failed Rebuild, want the previous handler", got)
+	}
+}
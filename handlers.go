@@ -0,0 +1,230 @@
+package plugger
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/go-openapi/swag"
+)
+
+// WithHandler sets the handler of the operation
+//
+// fn is either a function with the signature of the generated
+// handler func type, e.g. func(operations.GetGreetingParams) middleware.Responder,
+// or a value of the generated handler interface.
+// It is the same as assigning api.GetGreetingHandler, but the plug
+// reports an unknown operationId or a wrong signature as a configuration error.
+func WithHandler(operationID string, fn interface{}) Option {
+	return newOptionAPI(func(p *Plug) error {
+		op, ok, err := operationByID(p.apiv, operationID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownOperation, operationID)
+		}
+
+		key, ok := handlerField(p.apiv, op)
+		if !ok {
+			return &FieldError{
+				Target: reflect.Indirect(p.apiv).Type().String(),
+				Field:  swag.ToGoName(operationID) + "Handler",
+				Err:    ErrFieldNotFound,
+			}
+		}
+		return setHandler(p.apiv, key, fn)
+	})
+}
+
+// handlerField returns the name of the API field that holds the operation handler
+//
+// go-swagger names it after the operationId, and prefixes it
+// with the tag if operations are grouped by tags.
+func handlerField(apiv reflect.Value, op specOperation) (string, bool) {
+	api := reflect.Indirect(apiv)
+	name := swag.ToGoName(op.ID) + "Handler"
+
+	candidates := []string{name}
+	for _, tag := range op.Tags {
+		candidates = append(candidates, swag.ToGoName(tag)+name)
+	}
+	for _, key := range candidates {
+		if _, ok := api.Type().FieldByName(key); ok {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// setHandler sets the handler field, converting a function to the generated handler func type
+func setHandler(apiv reflect.Value, key string, fn interface{}) error {
+	api := reflect.Indirect(apiv)
+	field := api.FieldByName(key)
+	fv := reflect.ValueOf(fn)
+
+	fieldErr := func(err error) error {
+		return &FieldError{Target: api.Type().String(), Field: key, Err: err}
+	}
+
+	switch {
+	case !fv.IsValid() || isNil(fn) || fv.Kind() == reflect.Func && fv.IsNil():
+		return fieldErr(fmt.Errorf("%w: handler is nil", ErrFieldType))
+	case fv.Type().AssignableTo(field.Type()):
+		field.Set(fv)
+		return nil
+	}
+
+	// the generated New*API sets a handler func to every handler,
+	// its type tells the expected signature
+	funcType, ok := handlerFuncType(field)
+	if !ok {
+		return fieldErr(fmt.Errorf("%w: %s can't be used as %s", ErrFieldType, fv.Type(), field.Type()))
+	}
+	if fv.Kind() != reflect.Func || !fv.Type().ConvertibleTo(funcType) {
+		return fieldErr(fmt.Errorf("%w: %s must have signature %s", ErrFieldType, fv.Type(), funcSignature(funcType)))
+	}
+
+	field.Set(fv.Convert(funcType))
+	return nil
+}
+
+// handlerFuncType returns the generated handler func type of the handler field
+func handlerFuncType(field reflect.Value) (reflect.Type, bool) {
+	if field.Kind() != reflect.Interface || field.IsNil() {
+		return nil, false
+	}
+	t := field.Elem().Type()
+	if t.Kind() != reflect.Func || !t.Implements(field.Type()) {
+		return nil, false
+	}
+	return t, true
+}
+
+// funcSignature formats the underlying signature of a named func type
+func funcSignature(t reflect.Type) string {
+	in := make([]reflect.Type, t.NumIn())
+	for i := range in {
+		in[i] = t.In(i)
+	}
+	out := make([]reflect.Type, t.NumOut())
+	for i := range out {
+		out[i] = t.Out(i)
+	}
+	return reflect.FuncOf(in, out, t.IsVariadic()).String()
+}
+
+// ErrUnimplemented is returned by Serve if WithStrictHandlers is set
+// and some operations have no handler implemented
+var ErrUnimplemented = errors.New("plugger: operations are not implemented")
+
+// WithStrictHandlers makes the plug refuse to serve
+// if any operation isn't implemented, see Unimplemented
+func WithStrictHandlers() Option {
+	return newOptionPlug(func(p *Plug) error {
+		p.strictHandlers = true
+		return nil
+	})
+}
+
+// Unimplemented returns operationIds of the operations
+// that have no handler implemented
+//
+// go-swagger sets a handler responding with middleware.NotImplemented
+// to every operation in the generated New*API function.
+// Operations that still use such handlers, or have no handler at all, are reported.
+// Handlers are never called to tell so, so a handler of your own
+// responding with middleware.NotImplemented counts as implemented.
+// Operations of the APIs mounted with MountAPI are included.
+func (p *Plug) Unimplemented() ([]string, error) {
+	ops, err := specOperations(p.apiv)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, op := range ops {
+		key, ok := handlerField(p.apiv, op)
+		if !ok || p.isDefaultHandler(key) {
+			ids = append(ids, op.ID)
+		}
+	}
+
+	for _, sub := range p.mounts {
+		subIDs, err := sub.Unimplemented()
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, subIDs...)
+	}
+	return ids, nil
+}
+
+// checkHandlers reports operations without handlers before the plug is served
+func (p *Plug) checkHandlers() error {
+	ids, err := p.Unimplemented()
+	if err != nil {
+		// APIs without a spec can't be checked
+		return nil
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if p.strictHandlers {
+		return fmt.Errorf("%w: %s", ErrUnimplemented, strings.Join(ids, ", "))
+	}
+	for _, id := range ids {
+		p.log().Warn("operation is not implemented", "operation", id)
+	}
+	return nil
+}
+
+// isDefaultHandler checks if the handler field is empty
+// or still contains the handler set by the generated New*API function
+func (p *Plug) isDefaultHandler(key string) bool {
+	api := reflect.Indirect(p.apiv)
+	field := api.FieldByName(key)
+	if !field.IsValid() || field.IsNil() {
+		return true
+	}
+
+	fv := field.Elem()
+	if fv.Kind() != reflect.Func {
+		return false
+	}
+	if fv.IsNil() {
+		return true
+	}
+
+	// the generated handlers are closures declared in New*API,
+	// e.g. restapi/operations.NewGreetingServerAPI.func1,
+	// so they are told by the name without calling them
+	fn := runtime.FuncForPC(fv.Pointer())
+	if fn == nil {
+		return false
+	}
+	prefix := funcPkgPath(api.Type().PkgPath()) + ".New" + api.Type().Name() + ".func"
+	name := fn.Name()
+	return strings.HasPrefix(name, prefix) && isDigits(name[len(prefix):])
+}
+
+// funcPkgPath returns the package path the way it is written in function names,
+// dots of the last path element are escaped
+func funcPkgPath(pkg string) string {
+	i := strings.LastIndex(pkg, "/") + 1
+	return pkg[:i] + strings.Replace(pkg[i:], ".", "%2e", -1)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package plugger_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-openapi/runtime/middleware"
+
+	"github.com/ilyakaznacheev/go-plugger"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi/operations"
+)
+
+func TestUnimplemented(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(api *operations.GreetingServerAPI)
+		opts  []plugger.Option
+		want  []string
+	}{
+		{
+			name: "generated",
+			want: []string{"getGreeting"},
+		},
+		{
+			name:  "configureAPI",
+			setup: configureAPI,
+		},
+		{
+			name: "WithHandler",
+			opts: []plugger.Option{
+				plugger.WithHandler("getGreeting", func(operations.GetGreetingParams) middleware.Responder {
+					return operations.NewGetGreetingOK()
+				}),
+			},
+		},
+		{
+			// handlers aren't called, so a handler of the user is implemented
+			name: "not implemented",
+			setup: func(api *operations.GreetingServerAPI) {
+				api.GetGreetingHandler = operations.GetGreetingHandlerFunc(func(operations.GetGreetingParams) middleware.Responder {
+					return middleware.NotImplemented("later")
+				})
+			},
+		},
+		{
+			name: "other error",
+			setup: func(api *operations.GreetingServerAPI) {
+				api.GetGreetingHandler = operations.GetGreetingHandlerFunc(func(operations.GetGreetingParams) middleware.Responder {
+					return middleware.Error(404, "not found")
+				})
+			},
+		},
+		{
+			name: "nil",
+			setup: func(api *operations.GreetingServerAPI) {
+				api.GetGreetingHandler = nil
+			},
+			want: []string{"getGreeting"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newGreetingAPI(t)
+			if tt.setup != nil {
+				tt.setup(api)
+			}
+			p, err := plugger.NewAPIPlug(api, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := p.Unimplemented()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unimplemented() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnimplementedConfiguredLater(t *testing.T) {
+	// handlers can be set after the plug is created
+	api := newGreetingAPI(t)
+	p, err := plugger.New(restapi.NewServer(nil), api)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configureAPI(api)
+
+	got, err := p.Unimplemented()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Unimplemented() = %v, want none", got)
+	}
+}
+
+func TestUnimplementedDoesNotCallHandlers(t *testing.T) {
+	var calls int
+	api := newGreetingAPI(t)
+	api.GetGreetingHandler = operations.GetGreetingHandlerFunc(func(operations.GetGreetingParams) middleware.Responder {
+		calls++
+		return operations.NewGetGreetingOK()
+	})
+	p, err := plugger.NewAPIPlug(api, plugger.WithMockResponses())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := p.Unimplemented(); err != nil || len(got) != 0 {
+		t.Errorf("Unimplemented() = %v, %v, want none", got, err)
+	}
+	p.Handler()
+	p.Rebuild()
+	if calls != 0 {
+		t.Errorf("handler is called %d times without requests", calls)
+	}
+}
+
+func TestStrictHandlers(t *testing.T) {
+	api := newGreetingAPI(t)
+	p, err := plugger.New(restapi.NewServer(nil), api,
+		plugger.WithStrictHandlers(), plugger.WithoutSignalHandling(),
+		plugger.WithHost("127.0.0.1"), plugger.WithPort(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Serve(); !errors.Is(err, plugger.ErrUnimplemented) {
+		t.Fatalf("Serve() = %v, want %v", err, plugger.ErrUnimplemented)
+	}
+
+	api = newGreetingAPI(t)
+	configureAPI(api)
+	p, err = plugger.New(restapi.NewServer(nil), api,
+		plugger.WithStrictHandlers(), plugger.WithoutSignalHandling(),
+		plugger.WithHost("127.0.0.1"), plugger.WithPort(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.ServeContext(ctx); err != nil {
+		t.Fatalf("ServeContext() = %v, want nil", err)
+	}
+}
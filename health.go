@@ -0,0 +1,279 @@
+package plugger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// default health check settings
+const (
+	defaultLivenessPath  = "/healthz"
+	defaultReadinessPath = "/readyz"
+	defaultCheckTimeout  = 5 * time.Second
+)
+
+// ErrShuttingDown is reported by the readiness check once the plug is shutting down
+var ErrShuttingDown = errors.New("plugger: server is shutting down")
+
+// Checker checks a dependency of the API, e.g. a database connection
+type Checker interface {
+	// Name identifies the check in the health report
+	Name() string
+	// Check returns an error if the dependency is unhealthy.
+	// It must return once ctx is done
+	Check(ctx context.Context) error
+}
+
+type checkerFunc struct {
+	name string
+	f    func(context.Context) error
+}
+
+// NewChecker creates a Checker from a function
+func NewChecker(name string, f func(ctx context.Context) error) Checker {
+	return &checkerFunc{name: name, f: f}
+}
+
+func (c *checkerFunc) Name() string {
+	return c.name
+}
+
+func (c *checkerFunc) Check(ctx context.Context) error {
+	return c.f(ctx)
+}
+
+// HealthOption configures the health checks
+type HealthOption func(*healthChecks) error
+
+// ReadinessCheck adds a check to the readiness endpoint
+//
+// Use it for the dependencies the API can't serve requests without.
+func ReadinessCheck(c Checker) HealthOption {
+	return func(h *healthChecks) error {
+		if c == nil {
+			return errors.New("plugger: readiness checker is nil")
+		}
+		h.readiness = append(h.readiness, &cachedCheck{Checker: c})
+		return nil
+	}
+}
+
+// LivenessCheck adds a check to the liveness endpoint
+//
+// A failing liveness check usually makes the orchestrator restart
+// the process, so don't use it for external dependencies.
+func LivenessCheck(c Checker) HealthOption {
+	return func(h *healthChecks) error {
+		if c == nil {
+			return errors.New("plugger: liveness checker is nil")
+		}
+		h.liveness = append(h.liveness, &cachedCheck{Checker: c})
+		return nil
+	}
+}
+
+// HealthCheckTimeout sets a time limit of a single check, 5 seconds by default.
+// A check that doesn't finish in time fails.
+func HealthCheckTimeout(d time.Duration) HealthOption {
+	return func(h *healthChecks) error {
+		if d <= 0 {
+			return fmt.Errorf("plugger: health check timeout %v must be positive", d)
+		}
+		h.timeout = d
+		return nil
+	}
+}
+
+// HealthCheckCache reuses check results for the ttl,
+// so frequent probes don't overload the dependencies.
+// Results are not cached by default.
+func HealthCheckCache(ttl time.Duration) HealthOption {
+	return func(h *healthChecks) error {
+		h.ttl = ttl
+		return nil
+	}
+}
+
+// HealthPaths sets the liveness and the readiness endpoint paths,
+// "/healthz" and "/readyz" by default
+func HealthPaths(liveness, readiness string) HealthOption {
+	return func(h *healthChecks) error {
+		h.livenessPath, h.readinessPath = liveness, readiness
+		return nil
+	}
+}
+
+// HealthEndpoints sets options of the health endpoints,
+// e.g. the listeners they are served on
+func HealthEndpoints(opts ...EndpointOption) HealthOption {
+	return func(h *healthChecks) error {
+		h.endpointOpts = append(h.endpointOpts, opts...)
+		return nil
+	}
+}
+
+// WithHealthChecks serves the liveness and the readiness endpoints
+//
+// Both endpoints respond with 200 OK if all their checks pass,
+// and with 503 Service Unavailable otherwise. The response body is
+// a JSON report of every check. Checks run concurrently.
+//
+// The readiness endpoint starts failing as soon as the plug
+// is shutting down, so load balancers stop sending new requests
+// to the server while it drains.
+func WithHealthChecks(opts ...HealthOption) Option {
+	return newOptionRouter(func(p *Plug) error {
+		h := &healthChecks{
+			p:             p,
+			timeout:       defaultCheckTimeout,
+			livenessPath:  defaultLivenessPath,
+			readinessPath: defaultReadinessPath,
+		}
+		for _, opt := range opts {
+			if err := opt(h); err != nil {
+				return err
+			}
+		}
+
+		if err := p.addEndpoint(h.livenessPath, http.HandlerFunc(h.serveLiveness), h.endpointOpts); err != nil {
+			return err
+		}
+		return p.addEndpoint(h.readinessPath, http.HandlerFunc(h.serveReadiness), h.endpointOpts)
+	})
+}
+
+// healthChecks serves the health endpoints
+type healthChecks struct {
+	p *Plug
+
+	liveness  []*cachedCheck
+	readiness []*cachedCheck
+
+	timeout time.Duration
+	ttl     time.Duration
+
+	livenessPath  string
+	readinessPath string
+	endpointOpts  []EndpointOption
+}
+
+// healthReport is the health endpoint response
+type healthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkReport `json:"checks,omitempty"`
+}
+
+type checkReport struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// health report statuses
+const (
+	statusOK      = "ok"
+	statusFailing = "failing"
+)
+
+func (h *healthChecks) serveLiveness(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, h.run(r.Context(), h.liveness))
+}
+
+func (h *healthChecks) serveReadiness(w http.ResponseWriter, r *http.Request) {
+	report := h.run(r.Context(), h.readiness)
+	if h.p.shuttingDown() {
+		report.Status = statusFailing
+		if report.Checks == nil {
+			report.Checks = make(map[string]checkReport)
+		}
+		report.Checks["shutdown"] = checkReport{Status: statusFailing, Error: ErrShuttingDown.Error()}
+	}
+	h.serve(w, report)
+}
+
+func (h *healthChecks) serve(w http.ResponseWriter, report healthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != statusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// run runs the checks concurrently
+func (h *healthChecks) run(ctx context.Context, checks []*cachedCheck) healthReport {
+	report := healthReport{Status: statusOK}
+	if len(checks) == 0 {
+		return report
+	}
+
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *cachedCheck) {
+			defer wg.Done()
+			errs[i] = c.check(ctx, h.timeout, h.ttl)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report.Checks = make(map[string]checkReport, len(checks))
+	for i, c := range checks {
+		cr := checkReport{Status: statusOK}
+		if errs[i] != nil {
+			cr = checkReport{Status: statusFailing, Error: errs[i].Error()}
+			report.Status = statusFailing
+		}
+		report.Checks[c.Name()] = cr
+	}
+	return report
+}
+
+// cachedCheck keeps the last check result
+type cachedCheck struct {
+	Checker
+
+	mu      sync.Mutex
+	checked time.Time
+	err     error
+}
+
+// check runs the check, or returns the cached result if it is fresh enough.
+// Concurrent probes wait for a single check run.
+func (c *cachedCheck) check(ctx context.Context, timeout, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ttl > 0 && !c.checked.IsZero() && time.Since(c.checked) < ttl {
+		return c.err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("check timed out: %w", ctx.Err())
+	}
+
+	c.checked, c.err = time.Now(), err
+	return err
+}
+
+// shuttingDown checks if the plug shutdown has started
+func (p *Plug) shuttingDown() bool {
+	return atomic.LoadInt32(&p.stopping) == 1 || p.hooks.status().PreServerShutdown
+}
@@ -0,0 +1,46 @@
+package plugger_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ilyakaznacheev/go-plugger"
+)
+
+func TestWithHealthChecks(t *testing.T) {
+	var dbErr error
+	db := plugger.NewChecker("db", func(context.Context) error { return dbErr })
+
+	p, err := plugger.NewAPIPlug(newGreetingAPI(t), plugger.WithHealthChecks(plugger.ReadinessCheck(db)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := func(path string) int {
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	if got := status("/readyz"); got != http.StatusOK {
+		t.Errorf("readiness = %d, want 200", got)
+	}
+	dbErr = errors.New("no connection")
+	if got := status("/readyz"); got != http.StatusServiceUnavailable {
+		t.Errorf("readiness with a failing check = %d, want 503", got)
+	}
+	dbErr = nil
+
+	// the embedded plug has no server, but its readiness fails on shutdown
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := status("/readyz"); got != http.StatusServiceUnavailable {
+		t.Errorf("readiness after Shutdown = %d, want 503", got)
+	}
+	if got := status("/healthz"); got != http.StatusOK {
+		t.Errorf("liveness after Shutdown = %d, want 200", got)
+	}
+}
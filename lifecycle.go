@@ -0,0 +1,152 @@
+package plugger
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ErrShutdownIncomplete is used when the server has stopped,
+// but not all of its listeners were shut down gracefully
+var ErrShutdownIncomplete = errors.New("plugger: listeners were not shut down gracefully")
+
+// generated server logs listener shutdown errors with a format
+// starting with this prefix, e.g. "HTTP server Shutdown: %v"
+const shutdownErrPrefix = "HTTP server Shutdown"
+
+// ShutdownStatus tells which of the API shutdown hooks have been called
+type ShutdownStatus struct {
+	// PreServerShutdown is true if the API PreServerShutdown hook has been called
+	PreServerShutdown bool
+	// ServerShutdown is true if the API ServerShutdown hook has been called.
+	// The generated server calls it only if all listeners were shut down successfully
+	ServerShutdown bool
+}
+
+// ShutdownError is returned if the server was not shut down gracefully
+type ShutdownError struct {
+	ShutdownStatus
+
+	// Err is the real cause of the failure
+	Err error
+}
+
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("plugger: shutdown failed: %v (pre-shutdown hook called: %t, shutdown hook called: %t)",
+		e.Err, e.PreServerShutdown, e.ServerShutdown)
+}
+
+// Unwrap returns the real cause of the failure
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
+}
+
+// hookState tracks the API shutdown hooks
+//
+// The generated server doesn't report the result of its shutdown,
+// so the plug wraps the API hooks and the logger to find it out.
+type hookState struct {
+	// onPreShutdown is called once the server shutdown starts
+	onPreShutdown func()
+
+	mu          sync.Mutex
+	preShutdown bool
+	shutdown    bool
+	shutdownErr error
+}
+
+// install wraps the API hooks
+//
+// The server calls hooks of the main API only,
+// so hooks of the mounted APIs are called from them.
+func (h *hookState) install(apiv reflect.Value, mounted []reflect.Value) {
+	if pre, ok := getDynParam(apiv, "PreServerShutdown").(func()); ok {
+		mountedPre := collectHooks(mounted, "PreServerShutdown")
+		setDynParam(apiv, "PreServerShutdown", func() {
+			h.mu.Lock()
+			h.preShutdown = true
+			h.mu.Unlock()
+			if h.onPreShutdown != nil {
+				h.onPreShutdown()
+			}
+			pre()
+			for _, f := range mountedPre {
+				f()
+			}
+		})
+	}
+
+	if post, ok := getDynParam(apiv, "ServerShutdown").(func()); ok {
+		mountedPost := collectHooks(mounted, "ServerShutdown")
+		setDynParam(apiv, "ServerShutdown", func() {
+			h.mu.Lock()
+			h.shutdown = true
+			h.mu.Unlock()
+			post()
+			for _, f := range mountedPost {
+				f()
+			}
+		})
+	}
+}
+
+// captureLog finds listener shutdown errors in the generated server logs
+func (h *hookState) captureLog(f string, args []interface{}) {
+	if !strings.HasPrefix(f, shutdownErrPrefix) {
+		return
+	}
+	for _, arg := range args {
+		if err, ok := arg.(error); ok {
+			h.mu.Lock()
+			if h.shutdownErr == nil {
+				h.shutdownErr = err
+			}
+			h.mu.Unlock()
+			return
+		}
+	}
+}
+
+// collectHooks returns non-nil hooks of the APIs
+func collectHooks(apis []reflect.Value, key string) []func() {
+	var hooks []func()
+	for _, apiv := range apis {
+		if f, ok := getDynParam(apiv, key).(func()); ok && f != nil {
+			hooks = append(hooks, f)
+		}
+	}
+	return hooks
+}
+
+func (h *hookState) status() ShutdownStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return ShutdownStatus{
+		PreServerShutdown: h.preShutdown,
+		ServerShutdown:    h.shutdown,
+	}
+}
+
+// err returns a shutdown error of the stopped server
+func (h *hookState) err() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.shutdown {
+		return nil
+	}
+
+	err := h.shutdownErr
+	if err == nil {
+		err = ErrShutdownIncomplete
+	}
+	return &ShutdownError{
+		Err: err,
+		ShutdownStatus: ShutdownStatus{
+			PreServerShutdown: h.preShutdown,
+			ServerShutdown:    h.shutdown,
+		},
+	}
+}
@@ -0,0 +1,230 @@
+package plugger_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/runtime/middleware"
+
+	"github.com/ilyakaznacheev/go-plugger"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi/operations"
+)
+
+// slowPlug creates a plug with a handler that responds once release is closed.
+// entered receives a value when a request reaches the handler.
+func slowPlug(t *testing.T, gracefulTimeout time.Duration, opts ...plugger.Option) (p *plugger.Plug, entered chan struct{}, release chan struct{}) {
+	t.Helper()
+
+	entered, release = make(chan struct{}, 1), make(chan struct{})
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	})
+
+	p, err := plugger.New(restapi.NewServer(nil), newGreetingAPI(t), append([]plugger.Option{
+		plugger.WithHost("127.0.0.1"), plugger.WithPort(0),
+		plugger.WithoutSignalHandling(),
+		plugger.WithGracefulTimeout(gracefulTimeout),
+		plugger.WithHandler("getGreeting", func(operations.GetGreetingParams) middleware.Responder {
+			entered <- struct{}{}
+			<-release
+			return operations.NewGetGreetingOK().WithPayload("done")
+		}),
+	}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, entered, release
+}
+
+// callSlow sends a request to the plug and waits until it reaches the handler
+func callSlow(t *testing.T, p *plugger.Plug, entered <-chan struct{}) {
+	t.Helper()
+
+	go func() {
+		resp, err := http.Get("http://" + p.Addrs()["http"].String() + "/hello")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	select {
+	case <-entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request hasn't reached the handler")
+	}
+}
+
+// checkShutdownError checks the error is a *ShutdownError caused by cause
+func checkShutdownError(t *testing.T, err, cause error, want plugger.ShutdownStatus) {
+	t.Helper()
+
+	var shutdownErr *plugger.ShutdownError
+	if !errors.As(err, &shutdownErr) {
+		t.Fatalf("error = %v, want a *ShutdownError", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("error = %v, want %v", err, cause)
+	}
+	if shutdownErr.ShutdownStatus != want {
+		t.Errorf("status = %+v, want %+v", shutdownErr.ShutdownStatus, want)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	api := newGreetingAPI(t)
+	configureAPI(api)
+	p, err := plugger.New(restapi.NewServer(nil), api,
+		plugger.WithHost("127.0.0.1"), plugger.WithPort(0),
+		plugger.WithoutSignalHandling())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- p.Serve() }()
+	<-p.Ready()
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() = %v, want nil", err)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("Serve() = %v, want nil", err)
+	}
+	want := plugger.ShutdownStatus{PreServerShutdown: true, ServerShutdown: true}
+	if got := p.ShutdownStatus(); got != want {
+		t.Errorf("ShutdownStatus() = %+v, want %+v", got, want)
+	}
+}
+
+func TestShutdownGracefulTimeout(t *testing.T) {
+	p, entered, release := slowPlug(t, 100*time.Millisecond)
+	errc := make(chan error, 1)
+	go func() { errc <- p.Serve() }()
+	<-p.Ready()
+	callSlow(t, p, entered)
+
+	// the request is still served when GracefulTimeout expires
+	want := plugger.ShutdownStatus{PreServerShutdown: true}
+	checkShutdownError(t, p.Shutdown(context.Background()), context.DeadlineExceeded, want)
+	close(release)
+	checkShutdownError(t, <-errc, context.DeadlineExceeded, want)
+	if got := p.ShutdownStatus(); got != want {
+		t.Errorf("ShutdownStatus() = %+v, want %+v", got, want)
+	}
+}
+
+func TestShutdownContext(t *testing.T) {
+	p, entered, release := slowPlug(t, 10*time.Second)
+	errc := make(chan error, 1)
+	go func() { errc <- p.Serve() }()
+	<-p.Ready()
+	callSlow(t, p, entered)
+
+	// Shutdown stops waiting for the server once ctx is done
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	checkShutdownError(t, p.Shutdown(ctx), context.DeadlineExceeded,
+		plugger.ShutdownStatus{PreServerShutdown: true})
+
+	close(release)
+	if err := <-errc; err != nil {
+		t.Errorf("Serve() = %v, want nil once the request is served", err)
+	}
+}
+
+func TestServeContext(t *testing.T) {
+	api := newGreetingAPI(t)
+	configureAPI(api)
+	p, err := plugger.New(restapi.NewServer(nil), api,
+		plugger.WithHost("127.0.0.1"), plugger.WithPort(0),
+		plugger.WithoutSignalHandling())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- p.ServeContext(ctx) }()
+	<-p.Ready()
+
+	cancel()
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("ServeContext() = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeContext hasn't returned after ctx is cancelled")
+	}
+	want := plugger.ShutdownStatus{PreServerShutdown: true, ServerShutdown: true}
+	if got := p.ShutdownStatus(); got != want {
+		t.Errorf("ShutdownStatus() = %+v, want %+v", got, want)
+	}
+}
+
+func TestServeContextGracefulTimeout(t *testing.T) {
+	p, entered, _ := slowPlug(t, 100*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- p.ServeContext(ctx) }()
+	<-p.Ready()
+	callSlow(t, p, entered)
+
+	cancel()
+	checkShutdownError(t, <-errc, context.DeadlineExceeded, plugger.ShutdownStatus{PreServerShutdown: true})
+}
+
+func TestWithShutdownDelay(t *testing.T) {
+	if _, err := plugger.New(restapi.NewServer(nil), newGreetingAPI(t), plugger.WithShutdownDelay(-time.Second)); err == nil {
+		t.Error("no error for a negative delay")
+	}
+
+	const delay = 300 * time.Millisecond
+	api := newGreetingAPI(t)
+	configureAPI(api)
+	p, err := plugger.New(restapi.NewServer(nil), api,
+		plugger.WithHost("127.0.0.1"), plugger.WithPort(0),
+		plugger.WithoutSignalHandling(),
+		plugger.WithHealthChecks(),
+		plugger.WithShutdownDelay(delay))
+	if err != nil {
+		t.Fatal(err)
+	}
+	servePlug(t, p)
+	url := "http://" + p.Addrs()["http"].String()
+
+	start := time.Now()
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- p.Shutdown(context.Background()) }()
+
+	// the readiness check fails right away, but the API is still served
+	for deadline := time.Now().Add(delay / 2); ; time.Sleep(10 * time.Millisecond) {
+		code, err := getStatus(url + "/readyz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code == http.StatusServiceUnavailable {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("GET /readyz = %d during the delay, want 503", code)
+		}
+	}
+	if code, err := getStatus(url + "/hello"); err != nil || code != http.StatusOK {
+		t.Errorf("GET /hello = %d %v during the delay, want 200", code, err)
+	}
+
+	if err := <-shutdown; err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < delay {
+		t.Errorf("Shutdown() took %v, want at least %v", d, delay)
+	}
+}
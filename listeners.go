@@ -0,0 +1,310 @@
+package plugger
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-openapi/swag"
+)
+
+// listener schemes of the generated server
+const (
+	schemeHTTP  = "http"
+	schemeHTTPS = "https"
+	schemeUnix  = "unix"
+)
+
+// AdminScheme is the key of the admin listener address returned by Addrs
+const AdminScheme = "admin"
+
+// generated server listener fields by the scheme
+var listenerFields = []struct {
+	scheme string
+	field  string
+}{
+	{schemeHTTP, "httpServerL"},
+	{schemeHTTPS, "httpsServerL"},
+	{schemeUnix, "domainSocketL"},
+}
+
+// ErrNoListener is used when an enabled scheme has no injected listener
+var ErrNoListener = errors.New("plugger: enabled scheme has no listener")
+
+// ErrSchemeNotEnabled is used when a listener is injected for a scheme
+// the server doesn't serve
+var ErrSchemeNotEnabled = errors.New("plugger: listener scheme is not enabled")
+
+// WithListener serves the scheme on the pre-opened listener
+//
+// scheme is one of "http", "https" or "unix". The listener of the "https"
+// scheme must accept plain connections, TLS is set up by the server.
+// Use it to serve on a listener opened by a test, the socket activation
+// or another process.
+//
+// If listeners are injected, the server doesn't open any other ones,
+// so every enabled scheme must have a listener, and every listener
+// must have its scheme enabled. If no listeners are enabled
+// explicitly, the schemes of the injected listeners are enabled.
+func WithListener(scheme string, l net.Listener) Option {
+	return newOptionServer(func(p *Plug) error {
+		if l == nil {
+			return fmt.Errorf("plugger: %s listener is nil", scheme)
+		}
+		field, ok := listenerField(scheme)
+		if !ok {
+			return fmt.Errorf("plugger: unknown listener scheme %q", scheme)
+		}
+		if !dynField(p.sv, field).IsValid() {
+			return &FieldError{Target: reflect.Indirect(p.sv).Type().String(), Field: field, Err: ErrFieldNotFound}
+		}
+
+		if p.listeners == nil {
+			p.listeners = make(map[string]net.Listener)
+		}
+		p.listeners[scheme] = l
+		return nil
+	})
+}
+
+// Addrs returns the addresses the listeners are bound to by the scheme,
+// the admin listener address is returned by the AdminScheme key.
+// It returns nil until the plug is listening.
+func (p *Plug) Addrs() map[string]net.Addr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.addrs == nil {
+		return nil
+	}
+	addrs := make(map[string]net.Addr, len(p.addrs))
+	for scheme, addr := range p.addrs {
+		addrs[scheme] = addr
+	}
+	return addrs
+}
+
+// Ready returns a channel that is closed once all listeners,
+// including the admin one, accept connections
+func (p *Plug) Ready() <-chan struct{} {
+	return p.ready
+}
+
+func listenerField(scheme string) (string, bool) {
+	for _, lf := range listenerFields {
+		if lf.scheme == scheme {
+			return lf.field, true
+		}
+	}
+	return "", false
+}
+
+// listen opens the server and the admin listeners, and wraps them
+// to count connections and to find out when they are ready.
+// It must be called with p.mu locked.
+func (p *Plug) listen() error {
+	if err := p.activateSockets(); err != nil {
+		return err
+	}
+	if err := p.injectListeners(); err != nil {
+		return err
+	}
+	if err := p.s.Listen(); err != nil {
+		return err
+	}
+
+	type boundListener struct {
+		scheme string
+		field  reflect.Value
+		l      net.Listener
+	}
+	// the server serves the enabled schemes only,
+	// listeners of the other ones never get ready
+	enabled, _ := getDynParam(p.sv, "EnabledListeners").([]string)
+	var bound []boundListener
+	for _, lf := range listenerFields {
+		if len(enabled) > 0 && !swag.ContainsStrings(enabled, lf.scheme) {
+			continue
+		}
+		field := dynField(p.sv, lf.field)
+		if !field.IsValid() || field.IsNil() {
+			continue
+		}
+		l, ok := field.Interface().(net.Listener)
+		if !ok {
+			continue
+		}
+		bound = append(bound, boundListener{scheme: lf.scheme, field: field, l: l})
+	}
+
+	n := len(bound)
+	if p.admin != nil {
+		n++
+	}
+	ready := newReadyGroup(n, p.ready)
+
+	// the handoff listener wraps the bound one directly,
+	// so that stopping it can interrupt a pending Accept
+	handoff := func(l net.Listener) net.Listener {
+		if p.restartSignal == nil {
+			return l
+		}
+		h := newHandoffListener(l)
+		p.handoff = append(p.handoff, h)
+		return h
+	}
+
+	p.addrs = make(map[string]net.Addr, n)
+	p.bound = make(map[string]net.Listener, n)
+	for _, b := range bound {
+		p.addrs[b.scheme] = b.l.Addr()
+		p.bound[b.scheme] = b.l
+
+		l := handoff(b.l)
+		if p.metrics != nil {
+			l = p.metrics.wrap(b.scheme, l)
+		}
+		b.field.Set(reflect.ValueOf(ready.wrap(l)))
+	}
+
+	if p.admin != nil {
+		l, err := p.admin.listen(p.log(), func(l net.Listener) net.Listener {
+			return ready.wrap(handoff(l))
+		})
+		if err != nil {
+			for _, b := range bound {
+				b.l.Close()
+			}
+			return err
+		}
+		p.addrs[AdminScheme] = l.Addr()
+		p.bound[AdminScheme] = l
+	}
+	return nil
+}
+
+// injectListeners sets the listeners passed with WithListener to the server
+func (p *Plug) injectListeners() error {
+	if len(p.listeners) == 0 {
+		return nil
+	}
+
+	enabled, _ := getDynParam(p.sv, "EnabledListeners").([]string)
+	if len(enabled) == 0 {
+		for scheme := range p.listeners {
+			enabled = append(enabled, scheme)
+		}
+		sort.Strings(enabled)
+		if err := setDynParam(p.sv, "EnabledListeners", enabled); err != nil {
+			return err
+		}
+	}
+	for _, scheme := range enabled {
+		if _, ok := p.listeners[scheme]; !ok {
+			return fmt.Errorf("%w: %s", ErrNoListener, scheme)
+		}
+	}
+	for _, lf := range listenerFields {
+		if _, ok := p.listeners[lf.scheme]; ok && !swag.ContainsStrings(enabled, lf.scheme) {
+			return fmt.Errorf("%w: %s", ErrSchemeNotEnabled, lf.scheme)
+		}
+	}
+
+	for scheme, l := range p.listeners {
+		field, _ := listenerField(scheme)
+		dynField(p.sv, field).Set(reflect.ValueOf(l))
+	}
+	p.syncListenerFields()
+
+	// the server doesn't open its own listeners anymore
+	if hasListeners := dynField(p.sv, "hasListeners"); hasListeners.IsValid() {
+		hasListeners.SetBool(true)
+	}
+	return nil
+}
+
+// syncListenerFields sets the server fields the generated Listen sets
+// for the opened listeners
+func (p *Plug) syncListenerFields() {
+	if l, ok := p.listeners[schemeHTTP]; ok {
+		if host, port, err := swag.SplitHostPort(l.Addr().String()); err == nil {
+			setDynDefault(p.sv, "Host", host)
+			setDynDefault(p.sv, "Port", port)
+		}
+	}
+
+	if l, ok := p.listeners[schemeHTTPS]; ok {
+		if host, port, err := swag.SplitHostPort(l.Addr().String()); err == nil {
+			setDynDefault(p.sv, "TLSHost", host)
+			setDynDefault(p.sv, "TLSPort", port)
+		}
+		// TLS settings default to the HTTP ones
+		for tlsKey, key := range map[string]string{
+			"TLSListenLimit":  "ListenLimit",
+			"TLSKeepAlive":    "KeepAlive",
+			"TLSReadTimeout":  "ReadTimeout",
+			"TLSWriteTimeout": "WriteTimeout",
+		} {
+			if isZero(getDynParam(p.sv, tlsKey)) {
+				if v := getDynParam(p.sv, key); v != nil {
+					setDynDefault(p.sv, tlsKey, v)
+				}
+			}
+		}
+	}
+}
+
+func isZero(v interface{}) bool {
+	switch val := v.(type) {
+	case int:
+		return val == 0
+	case time.Duration:
+		return val == 0
+	default:
+		return false
+	}
+}
+
+// readyGroup closes the channel once all listeners accept connections
+type readyGroup struct {
+	mu      sync.Mutex
+	pending int
+	ready   chan struct{}
+}
+
+func newReadyGroup(n int, ready chan struct{}) *readyGroup {
+	g := &readyGroup{pending: n, ready: ready}
+	if n == 0 {
+		close(ready)
+	}
+	return g
+}
+
+func (g *readyGroup) done() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.pending--
+	if g.pending == 0 {
+		close(g.ready)
+	}
+}
+
+// wrap notifies the group when the listener accepts connections for the first time
+func (g *readyGroup) wrap(l net.Listener) net.Listener {
+	return &readyListener{Listener: l, g: g}
+}
+
+type readyListener struct {
+	net.Listener
+	g    *readyGroup
+	once sync.Once
+}
+
+func (l *readyListener) Accept() (net.Conn, error) {
+	l.once.Do(l.g.done)
+	return l.Listener.Accept()
+}
@@ -0,0 +1,97 @@
+package plugger_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ilyakaznacheev/go-plugger"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi"
+)
+
+// localListener opens a listener closed at the end of the test.
+// Cleanups run in reverse, so it is closed after the plug is shut down.
+func localListener(t *testing.T) net.Listener {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func TestWithListener(t *testing.T) {
+	if _, err := plugger.New(restapi.NewServer(nil), newGreetingAPI(t), plugger.WithListener("http", nil)); err == nil {
+		t.Error("no error for a nil listener")
+	}
+
+	l := localListener(t)
+	if _, err := plugger.New(restapi.NewServer(nil), newGreetingAPI(t), plugger.WithListener("ftp", l)); err == nil {
+		t.Error("no error for an unknown scheme")
+	}
+
+	api := newGreetingAPI(t)
+	configureAPI(api)
+	p, err := plugger.New(restapi.NewServer(nil), api,
+		plugger.WithListener("http", l), plugger.WithoutSignalHandling())
+	if err != nil {
+		t.Fatal(err)
+	}
+	servePlug(t, p)
+
+	if got := p.Addrs()["http"]; got == nil || got.String() != l.Addr().String() {
+		t.Errorf("http address = %v, want %v", got, l.Addr())
+	}
+	resp, err := http.Get("http://" + l.Addr().String() + "/hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /hello = %d", resp.StatusCode)
+	}
+}
+
+func TestWithListenerSchemes(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled []string
+		schemes []string
+		want    error
+	}{
+		{"scheme is not enabled", []string{"http"}, []string{"http", "https"}, plugger.ErrSchemeNotEnabled},
+		{"enabled scheme has no listener", []string{"http", "https"}, []string{"http"}, plugger.ErrNoListener},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []plugger.Option{plugger.WithEnabledListeners(tt.enabled), plugger.WithoutSignalHandling()}
+			for _, scheme := range tt.schemes {
+				opts = append(opts, plugger.WithListener(scheme, localListener(t)))
+			}
+			p, err := plugger.New(restapi.NewServer(nil), newGreetingAPI(t), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			errc := make(chan error, 1)
+			go func() { errc <- p.Serve() }()
+			select {
+			case err := <-errc:
+				if !errors.Is(err, tt.want) {
+					t.Errorf("Serve() = %v, want %v", err, tt.want)
+				}
+			case <-p.Ready():
+				p.Shutdown(context.Background())
+				t.Fatal("plug is ready")
+			case <-time.After(5 * time.Second):
+				t.Fatal("Serve hasn't returned")
+			}
+		})
+	}
+}
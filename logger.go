@@ -0,0 +1,182 @@
+package plugger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+	"strings"
+)
+
+// Logger is a levelled logger with key-value pairs
+//
+// keyvals is a list of alternating keys and values,
+// e.g. logger.Info("request served", "status", 200).
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// stdLogger is a Logger based on the standard log package
+type stdLogger struct {
+	l *log.Logger
+}
+
+// NewStdLogger creates a Logger that writes to the standard logger l
+//
+// Messages are written as "LEVEL message key=value ...".
+// If l is nil, the default logger of the log package is used.
+func NewStdLogger(l *log.Logger) Logger {
+	return &stdLogger{l: l}
+}
+
+func (s *stdLogger) Debug(msg string, keyvals ...interface{}) {
+	s.output("DEBUG", msg, keyvals)
+}
+
+func (s *stdLogger) Info(msg string, keyvals ...interface{}) {
+	s.output("INFO", msg, keyvals)
+}
+
+func (s *stdLogger) Warn(msg string, keyvals ...interface{}) {
+	s.output("WARN", msg, keyvals)
+}
+
+func (s *stdLogger) Error(msg string, keyvals ...interface{}) {
+	s.output("ERROR", msg, keyvals)
+}
+
+func (s *stdLogger) output(level, msg string, keyvals []interface{}) {
+	var buf bytes.Buffer
+	buf.WriteString(level)
+	buf.WriteByte(' ')
+	buf.WriteString(msg)
+
+	for i := 0; i < len(keyvals); i += 2 {
+		var val interface{} = "(MISSING)"
+		if i+1 < len(keyvals) {
+			val = keyvals[i+1]
+		}
+		fmt.Fprintf(&buf, " %v=%v", keyvals[i], val)
+	}
+
+	if s.l != nil {
+		s.l.Output(3, buf.String())
+		return
+	}
+	log.Output(3, buf.String())
+}
+
+// WithStructuredLogger sets a levelled logger for the plug
+//
+// The generated server logs are routed through it as well:
+// errors and fatal messages with the Error level, the rest with the Info level.
+// It takes precedence over the API logger set with WithLogger.
+func WithStructuredLogger(l Logger) Option {
+	return newOptionPlug(func(p *Plug) error {
+		p.logger = l
+		return nil
+	})
+}
+
+// WithoutFatalExit stops the generated server from exiting the process
+//
+// The generated server calls os.Exit in Fatalf, e.g. if a listener fails.
+// With this option the fatal message is logged, the server is shut down
+// and the error is returned from Serve instead.
+// Use it if the plug runs embedded in a bigger application.
+func WithoutFatalExit() Option {
+	return newOptionPlug(func(p *Plug) error {
+		p.noFatalExit = true
+		return nil
+	})
+}
+
+// log returns the plug logger
+func (p *Plug) log() Logger {
+	if p.logger != nil {
+		return p.logger
+	}
+	return NewStdLogger(nil)
+}
+
+// installLogger routes the generated server logs through the plug
+func (p *Plug) installLogger() {
+	apiLogger, _ := getDynParam(p.apiv, "Logger").(func(string, ...interface{}))
+
+	setDynParam(p.apiv, "Logger", func(f string, args ...interface{}) {
+		p.hooks.captureLog(f, args)
+		fatal := calledFromFatalf()
+		if fatal && p.noFatalExit && p.failed() {
+			// servers fail to accept once the listeners are closed
+			runtime.Goexit()
+		}
+		msg := fmt.Sprintf(f, args...)
+
+		switch {
+		case p.logger != nil && (fatal || strings.HasPrefix(f, shutdownErrPrefix)):
+			p.logger.Error(msg)
+		case p.logger != nil:
+			p.logger.Info(msg)
+		case apiLogger != nil:
+			apiLogger(f, args...)
+		default:
+			log.Print(msg)
+		}
+
+		if fatal && p.noFatalExit {
+			p.fatal(errors.New(msg))
+		}
+	})
+}
+
+// fatal stops the server instead of exiting the process.
+// It is called from the generated Fatalf, so it ends the calling
+// goroutine to prevent os.Exit. If that is the goroutine of the
+// generated Serve, the servers it has started are never shut down,
+// so the listeners are closed by closeListeners.
+func (p *Plug) fatal(err error) {
+	p.mu.Lock()
+	if p.fatalErr == nil {
+		p.fatalErr = err
+	}
+	p.mu.Unlock()
+
+	p.s.Shutdown()
+	runtime.Goexit()
+}
+
+// closeListeners closes the server listeners after a fatal error
+func (p *Plug) closeListeners() {
+	if !p.failed() {
+		return
+	}
+
+	for scheme, l := range p.bound {
+		// the admin listener is closed by its own shutdown
+		if scheme != AdminScheme {
+			l.Close()
+		}
+	}
+}
+
+// failed checks if the server has stopped with a fatal error
+func (p *Plug) failed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.fatalErr != nil
+}
+
+// calledFromFatalf checks if the logger is called by the generated server Fatalf
+func calledFromFatalf() bool {
+	// skip runtime.Callers, calledFromFatalf and the logger itself
+	pcs := make([]uintptr, 1)
+	if runtime.Callers(3, pcs) == 0 {
+		return false
+	}
+	frame, _ := runtime.CallersFrames(pcs).Next()
+	return strings.HasSuffix(frame.Function, ".Fatalf")
+}
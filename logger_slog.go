@@ -0,0 +1,45 @@
+//go:build go1.21
+// +build go1.21
+
+package plugger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// slogLogger is a Logger based on the log/slog package
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger creates a Logger that writes to the slog logger l
+//
+// If l is nil, the default slog logger is used.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return &slogLogger{l: l}
+}
+
+func (s *slogLogger) Debug(msg string, keyvals ...interface{}) {
+	s.log(slog.LevelDebug, msg, keyvals)
+}
+
+func (s *slogLogger) Info(msg string, keyvals ...interface{}) {
+	s.log(slog.LevelInfo, msg, keyvals)
+}
+
+func (s *slogLogger) Warn(msg string, keyvals ...interface{}) {
+	s.log(slog.LevelWarn, msg, keyvals)
+}
+
+func (s *slogLogger) Error(msg string, keyvals ...interface{}) {
+	s.log(slog.LevelError, msg, keyvals)
+}
+
+func (s *slogLogger) log(level slog.Level, msg string, keyvals []interface{}) {
+	l := s.l
+	if l == nil {
+		l = slog.Default()
+	}
+	l.Log(context.Background(), level, msg, keyvals...)
+}
@@ -0,0 +1,106 @@
+package plugger_test
+
+import (
+	"bytes"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ilyakaznacheev/go-plugger"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi"
+)
+
+// recordLogger keeps messages of the structured logger
+type recordLogger struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (l *recordLogger) record(level, msg string) {
+	l.mu.Lock()
+	l.msgs = append(l.msgs, level+" "+msg)
+	l.mu.Unlock()
+}
+
+func (l *recordLogger) Debug(msg string, _ ...interface{}) { l.record("DEBUG", msg) }
+func (l *recordLogger) Info(msg string, _ ...interface{})  { l.record("INFO", msg) }
+func (l *recordLogger) Warn(msg string, _ ...interface{})  { l.record("WARN", msg) }
+func (l *recordLogger) Error(msg string, _ ...interface{}) { l.record("ERROR", msg) }
+
+func (l *recordLogger) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.msgs, "\n")
+}
+
+func TestWithStructuredLogger(t *testing.T) {
+	logger := &recordLogger{}
+	api := newGreetingAPI(t)
+	configureAPI(api)
+	p, err := plugger.New(restapi.NewServer(nil), api,
+		plugger.WithHost("127.0.0.1"), plugger.WithPort(0),
+		plugger.WithoutSignalHandling(),
+		plugger.WithStructuredLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	servePlug(t, p)
+
+	// the generated server logs are routed through the plug logger
+	if !strings.Contains(logger.String(), "INFO Serving greeting server at") {
+		t.Errorf("server logs are not routed:\n%s", logger)
+	}
+}
+
+func TestNewStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := plugger.NewStdLogger(log.New(&buf, "", 0))
+
+	logger.Info("listening", "addr", "127.0.0.1:80", "scheme")
+	logger.Error("failed")
+	want := "INFO listening addr=127.0.0.1:80 scheme=(MISSING)\nERROR failed\n"
+	if buf.String() != want {
+		t.Errorf("logged %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWithoutFatalExit(t *testing.T) {
+	api := newGreetingAPI(t)
+	configureAPI(api)
+
+	// https without a certificate is a fatal error of the generated Serve
+	p, err := plugger.New(restapi.NewServer(nil), api,
+		plugger.WithEnabledListeners([]string{"http", "https"}),
+		plugger.WithHost("127.0.0.1"), plugger.WithPort(0), plugger.WithTLSPort(0),
+		plugger.WithAdminListener("tcp", "127.0.0.1:0"),
+		plugger.WithoutFatalExit(), plugger.WithoutSignalHandling())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- p.Serve() }()
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Fatal("Serve() = nil, want the fatal error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve() hasn't returned after the fatal error")
+	}
+
+	addrs := p.Addrs()
+	for _, scheme := range []string{"http", "https", plugger.AdminScheme} {
+		addr, ok := addrs[scheme]
+		if !ok {
+			t.Fatalf("no %s address", scheme)
+		}
+		if conn, err := net.Dial(addr.Network(), addr.String()); err == nil {
+			conn.Close()
+			t.Errorf("%s listener at %s is still open", scheme, addr)
+		}
+	}
+}
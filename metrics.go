@@ -0,0 +1,301 @@
+package plugger
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	chimw "github.com/go-chi/chi/middleware"
+)
+
+// metricsContentType is the Prometheus text exposition format
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// unknownOperation labels requests that don't match any API operation
+const unknownOperation = "unknown"
+
+// latencyBuckets are upper bounds of the request duration histogram in seconds
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// WithMetrics collects request metrics and serves them at path
+// in the Prometheus text format
+//
+// The plug collects:
+//
+//   - plugger_http_requests_total, a counter of served requests
+//   - plugger_http_request_errors_total, a counter of requests that
+//     ended with a 5xx status, client errors are only told by the code label
+//   - plugger_http_request_duration_seconds, a histogram of request latency
+//   - plugger_http_requests_in_flight, a gauge of requests being served
+//   - plugger_listener_connections_total and plugger_listener_connections_active,
+//     connection counters of the server listeners
+//
+// Request metrics are labelled by the operationId and the status code
+// rather than the request path, so the number of series stays bounded.
+// Requests that don't match any operation are labelled with the "unknown" operation.
+// The endpoint options restrict the listeners the metrics are served on.
+func WithMetrics(path string, opts ...EndpointOption) Option {
+	return newOptionRouter(func(p *Plug) error {
+		if p.metrics != nil {
+			return fmt.Errorf("plugger: metrics are already served at %q", p.metrics.path)
+		}
+
+		mt := newMetrics(path)
+		if err := p.addEndpoint(path, mt, opts); err != nil {
+			return err
+		}
+		p.metrics = mt
+		p.r.Use(mt.middleware)
+		return nil
+	})
+}
+
+// requestLabels are labels of request metrics
+type requestLabels struct {
+	operation string
+	code      int
+}
+
+// requestStats are metrics of requests with the same labels
+type requestStats struct {
+	count   uint64
+	errors  uint64
+	buckets []uint64
+	sum     float64
+}
+
+// connStats are metrics of a listener
+type connStats struct {
+	accepted uint64
+	active   int64
+}
+
+// metrics collects the plug metrics
+type metrics struct {
+	path string
+
+	mu       sync.Mutex
+	requests map[requestLabels]*requestStats
+	inFlight map[string]int64
+	conns    map[string]*connStats
+}
+
+func newMetrics(path string) *metrics {
+	return &metrics{
+		path:     path,
+		requests: make(map[requestLabels]*requestStats),
+		inFlight: make(map[string]int64),
+		conns:    make(map[string]*connStats),
+	}
+}
+
+func (m *metrics) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// scrapes are not API requests
+		if r.URL.Path == m.path {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		operation := unknownOperation
+		if op, ok := OperationFrom(r.Context()); ok {
+			operation = op.ID
+		}
+
+		m.addInFlight(operation, 1)
+		defer m.addInFlight(operation, -1)
+
+		ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		failed := status >= http.StatusInternalServerError
+		m.observe(requestLabels{operation: operation, code: status}, time.Since(start), failed)
+	})
+}
+
+func (m *metrics) addInFlight(operation string, n int64) {
+	m.mu.Lock()
+	m.inFlight[operation] += n
+	m.mu.Unlock()
+}
+
+func (m *metrics) observe(labels requestLabels, d time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.requests[labels]
+	if !ok {
+		st = &requestStats{buckets: make([]uint64, len(latencyBuckets))}
+		m.requests[labels] = st
+	}
+
+	st.count++
+	if failed {
+		st.errors++
+	}
+	sec := d.Seconds()
+	st.sum += sec
+	for i, le := range latencyBuckets {
+		if sec <= le {
+			st.buckets[i]++
+		}
+	}
+}
+
+// listener returns connection metrics of the listener
+func (m *metrics) listener(scheme string) *connStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.conns[scheme]
+	if !ok {
+		st = &connStats{}
+		m.conns[scheme] = st
+	}
+	return st
+}
+
+// wrap counts connections of the listener
+func (m *metrics) wrap(scheme string, l net.Listener) net.Listener {
+	return &countingListener{Listener: l, st: m.listener(scheme)}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+	bw := bufio.NewWriter(w)
+	m.write(bw)
+	bw.Flush()
+}
+
+func (m *metrics) write(w *bufio.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	labels := make([]requestLabels, 0, len(m.requests))
+	for l := range m.requests {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].operation != labels[j].operation {
+			return labels[i].operation < labels[j].operation
+		}
+		return labels[i].code < labels[j].code
+	})
+
+	writeHeader(w, "plugger_http_requests_total", "counter", "Total number of served requests.")
+	for _, l := range labels {
+		fmt.Fprintf(w, "plugger_http_requests_total{%s} %d\n", l, m.requests[l].count)
+	}
+
+	writeHeader(w, "plugger_http_request_errors_total", "counter", "Total number of requests that failed with a 5xx status.")
+	for _, l := range labels {
+		fmt.Fprintf(w, "plugger_http_request_errors_total{%s} %d\n", l, m.requests[l].errors)
+	}
+
+	writeHeader(w, "plugger_http_request_duration_seconds", "histogram", "Request latency in seconds.")
+	for _, l := range labels {
+		st := m.requests[l]
+		for i, le := range latencyBuckets {
+			fmt.Fprintf(w, "plugger_http_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n",
+				l, formatFloat(le), st.buckets[i])
+		}
+		fmt.Fprintf(w, "plugger_http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", l, st.count)
+		fmt.Fprintf(w, "plugger_http_request_duration_seconds_sum{%s} %s\n", l, formatFloat(st.sum))
+		fmt.Fprintf(w, "plugger_http_request_duration_seconds_count{%s} %d\n", l, st.count)
+	}
+
+	writeHeader(w, "plugger_http_requests_in_flight", "gauge", "Number of requests being served.")
+	for _, op := range sortedKeys(m.inFlight) {
+		fmt.Fprintf(w, "plugger_http_requests_in_flight{operation=\"%s\"} %d\n",
+			escapeLabel(op), m.inFlight[op])
+	}
+
+	schemes := make([]string, 0, len(m.conns))
+	for s := range m.conns {
+		schemes = append(schemes, s)
+	}
+	sort.Strings(schemes)
+
+	writeHeader(w, "plugger_listener_connections_total", "counter", "Total number of accepted connections.")
+	for _, s := range schemes {
+		fmt.Fprintf(w, "plugger_listener_connections_total{listener=\"%s\"} %d\n",
+			s, atomic.LoadUint64(&m.conns[s].accepted))
+	}
+
+	writeHeader(w, "plugger_listener_connections_active", "gauge", "Number of open connections.")
+	for _, s := range schemes {
+		fmt.Fprintf(w, "plugger_listener_connections_active{listener=\"%s\"} %d\n",
+			s, atomic.LoadInt64(&m.conns[s].active))
+	}
+}
+
+// String formats the labels for the text format
+func (l requestLabels) String() string {
+	return fmt.Sprintf("operation=\"%s\",code=\"%d\"", escapeLabel(l.operation), l.code)
+}
+
+func writeHeader(w *bufio.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func sortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// countingListener counts connections of a listener
+type countingListener struct {
+	net.Listener
+	st *connStats
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddUint64(&l.st.accepted, 1)
+	atomic.AddInt64(&l.st.active, 1)
+	return &countingConn{Conn: conn, st: l.st}, nil
+}
+
+// countingConn decrements the active connections once closed
+type countingConn struct {
+	net.Conn
+	st     *connStats
+	closed int32
+}
+
+func (c *countingConn) Close() error {
+	if atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		atomic.AddInt64(&c.st.active, -1)
+	}
+	return c.Conn.Close()
+}
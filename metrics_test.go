@@ -0,0 +1,50 @@
+package plugger_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/runtime/middleware"
+
+	"github.com/ilyakaznacheev/go-plugger"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi/operations"
+)
+
+func TestWithMetrics(t *testing.T) {
+	handler := func(params operations.GetGreetingParams) middleware.Responder {
+		if params.Name != nil && *params.Name == "fail" {
+			return middleware.Error(http.StatusInternalServerError, "failed")
+		}
+		return operations.NewGetGreetingOK().WithPayload("hello")
+	}
+	p, err := plugger.NewAPIPlug(newGreetingAPI(t),
+		plugger.WithHandler("getGreeting", handler),
+		plugger.WithMetrics("/metrics"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := p.Handler()
+
+	for _, target := range []string{"/hello", "/hello?name=fail", "/nowhere"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, line := range []string{
+		`plugger_http_requests_total{operation="getGreeting",code="200"} 1`,
+		`plugger_http_requests_total{operation="getGreeting",code="500"} 1`,
+		`plugger_http_requests_total{operation="unknown",code="404"} 1`,
+		`plugger_http_request_errors_total{operation="getGreeting",code="200"} 0`,
+		`plugger_http_request_errors_total{operation="getGreeting",code="500"} 1`,
+		`plugger_http_request_errors_total{operation="unknown",code="404"} 0`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics don't contain %s:\n%s", line, body)
+		}
+	}
+}
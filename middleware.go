@@ -0,0 +1,203 @@
+package plugger
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-openapi/runtime/middleware"
+)
+
+// Phase defines when a middleware runs while serving a request
+//
+// A request passes the phases in this order:
+//
+//   - BeforeRouting middleware
+//   - go-swagger routing
+//   - AfterRouting middleware
+//   - operation middleware, see WithOperationMiddleware
+//   - authentication and authorization
+//   - AfterAuthentication middleware
+//   - binding and validation of the request and the operation handler
+type Phase int
+
+const (
+	// BeforeRouting middleware runs on the plug router
+	// before the request is matched to an API operation
+	BeforeRouting Phase = iota
+	// AfterRouting middleware runs after go-swagger has matched the operation.
+	// The route is available with middleware.MatchedRouteFrom
+	AfterRouting
+	// AfterAuthentication middleware runs after the request is authenticated
+	// and authorized. The principal is available with middleware.SecurityPrincipalFrom
+	AfterAuthentication
+)
+
+// WithMiddleware adds middleware to the plug router
+//
+// It is the same as WithPhaseMiddleware(BeforeRouting, mw...).
+// Unlike Router().Use it can be used before the API is mounted.
+func WithMiddleware(mw ...func(http.Handler) http.Handler) Option {
+	return WithPhaseMiddleware(BeforeRouting, mw...)
+}
+
+// WithPhaseMiddleware adds middleware that runs at the phase of the request
+//
+// Middleware of the same phase run in the order they are passed,
+// the first one is the outermost.
+func WithPhaseMiddleware(phase Phase, mw ...func(http.Handler) http.Handler) Option {
+	switch phase {
+	case BeforeRouting:
+		return newOptionRouter(func(p *Plug) error {
+			p.r.Use(mw...)
+			return nil
+		})
+	case AfterRouting:
+		return newOptionPlug(func(p *Plug) error {
+			p.routedMiddleware = append(p.routedMiddleware, mw...)
+			return nil
+		})
+	case AfterAuthentication:
+		return newOptionPlug(func(p *Plug) error {
+			if _, err := specOf(p.apiv); err != nil {
+				return err
+			}
+			p.authMiddleware = append(p.authMiddleware, mw...)
+			return nil
+		})
+	default:
+		return newOptionPlug(func(*Plug) error {
+			return fmt.Errorf("plugger: unknown middleware phase %d", phase)
+		})
+	}
+}
+
+// operationMiddleware is a middleware attached to a single API operation
+type operationMiddleware struct {
+	method string
+	path   string
+	mw     []func(http.Handler) http.Handler
+}
+
+// WithOperationMiddleware attaches middleware to the operation with the operationId
+//
+// The middleware runs after go-swagger routing, but before
+// authentication, binding and validation of the request.
+// Middleware of the operation, including the ones attached with WithTagMiddleware,
+// run in the order they are passed, the first one is the outermost.
+// The plug can't be created if the spec has no such operation.
+func WithOperationMiddleware(operationID string, mw ...func(http.Handler) http.Handler) Option {
+	return newOptionPlug(func(p *Plug) error {
+		op, ok, err := operationByID(p.apiv, operationID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownOperation, operationID)
+		}
+
+		p.opMiddleware = append(p.opMiddleware, operationMiddleware{
+			method: op.Method,
+			path:   op.Path,
+			mw:     mw,
+		})
+		return nil
+	})
+}
+
+// WithTagMiddleware attaches middleware to all operations with the tag
+//
+// It works as WithOperationMiddleware for each of the operations.
+// The plug can't be created if no operation has such tag.
+func WithTagMiddleware(tag string, mw ...func(http.Handler) http.Handler) Option {
+	return newOptionPlug(func(p *Plug) error {
+		ops, err := operationsByTag(p.apiv, tag)
+		if err != nil {
+			return err
+		}
+		if len(ops) == 0 {
+			return fmt.Errorf("%w: %q", ErrUnknownTag, tag)
+		}
+
+		for _, op := range ops {
+			p.opMiddleware = append(p.opMiddleware, operationMiddleware{
+				method: op.Method,
+				path:   op.Path,
+				mw:     mw,
+			})
+		}
+		return nil
+	})
+}
+
+// addOperationMiddleware attaches middleware to the API handler cache.
+// It must be called before the API is served, because
+// go-swagger copies handlers to its router on the first Serve.
+func (p *Plug) addOperationMiddleware() {
+	if len(p.opMiddleware) == 0 && len(p.authMiddleware) == 0 {
+		return
+	}
+
+	p.api.Init()
+
+	// authentication runs inside of the operation handler,
+	// so these middleware are attached first to be the innermost
+	if len(p.authMiddleware) > 0 {
+		ops, _ := specOperations(p.apiv)
+		authMW := authenticated(p.api.Context(), p.authMiddleware)
+		for _, op := range ops {
+			p.api.AddMiddlewareFor(op.Method, op.Path, authMW)
+		}
+	}
+
+	// every call wraps the previous ones, so the middleware
+	// attached first are added last to be the outermost
+	for i := len(p.opMiddleware) - 1; i >= 0; i-- {
+		om := p.opMiddleware[i]
+		p.api.AddMiddlewareFor(om.method, om.path, func(h http.Handler) http.Handler {
+			return chain(h, om.mw)
+		})
+	}
+}
+
+// routedBuilder wraps the go-swagger operation executor
+// with AfterRouting middleware
+func (p *Plug) routedBuilder(h http.Handler) http.Handler {
+	return chain(h, p.routedMiddleware)
+}
+
+// authenticated runs the middleware after the request authentication
+//
+// The principal is stored in the request context by go-swagger,
+// so the operation handler doesn't authenticate the request again.
+func authenticated(ctx *middleware.Context, mw []func(http.Handler) http.Handler) middleware.Builder {
+	return func(next http.Handler) http.Handler {
+		wrapped := chain(next, mw)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, rCtx, ok := ctx.RouteInfo(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			r = rCtx
+
+			_, authReq, err := ctx.Authorize(r, route)
+			if err != nil {
+				ctx.Respond(w, r, route.Produces, route, err)
+				return
+			}
+			if authReq != nil {
+				r = authReq
+			}
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
+
+// chain wraps the handler with middleware, the first one is the outermost
+func chain(h http.Handler, mw []func(http.Handler) http.Handler) http.Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
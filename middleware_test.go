@@ -0,0 +1,96 @@
+package plugger_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ilyakaznacheev/go-plugger"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi/operations"
+)
+
+// taggedGreetingAPI creates the example API with the operation tagged
+func taggedGreetingAPI(t *testing.T, tag string) *operations.GreetingServerAPI {
+	t.Helper()
+
+	return editedGreetingAPI(t, func(_, op map[string]interface{}) {
+		op["tags"] = []string{tag}
+	})
+}
+
+// markOrder returns a middleware factory that records the order middleware run in
+func markOrder(order *[]string) func(name string) func(http.Handler) http.Handler {
+	return func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				*order = append(*order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+}
+
+func TestOperationMiddleware(t *testing.T) {
+	var order []string
+	mark := markOrder(&order)
+
+	p, err := plugger.NewAPIPlug(taggedGreetingAPI(t, "greeting"),
+		plugger.WithTagMiddleware("greeting", mark("tag")),
+		plugger.WithOperationMiddleware("getGreeting", mark("operation 1"), mark("operation 2")),
+		plugger.WithOperationMiddleware("getGreeting", mark("operation 3")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hello", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /hello = %d", rec.Code)
+	}
+	want := []string{"tag", "operation 1", "operation 2", "operation 3"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("middleware order = %v, want %v", order, want)
+	}
+}
+
+func TestOperationMiddlewareUnknown(t *testing.T) {
+	noop := func(next http.Handler) http.Handler { return next }
+
+	_, err := plugger.NewAPIPlug(newGreetingAPI(t), plugger.WithOperationMiddleware("getFarewell", noop))
+	if !configErrorIs(err, plugger.ErrUnknownOperation) {
+		t.Errorf("WithOperationMiddleware: NewAPIPlug() = %v, want %v", err, plugger.ErrUnknownOperation)
+	}
+	_, err = plugger.NewAPIPlug(newGreetingAPI(t), plugger.WithTagMiddleware("greeting", noop))
+	if !configErrorIs(err, plugger.ErrUnknownTag) {
+		t.Errorf("WithTagMiddleware: NewAPIPlug() = %v, want %v", err, plugger.ErrUnknownTag)
+	}
+}
+
+func TestPhaseMiddleware(t *testing.T) {
+	var order []string
+	mark := markOrder(&order)
+
+	p, err := plugger.NewAPIPlug(taggedGreetingAPI(t, "greeting"),
+		plugger.WithPhaseMiddleware(plugger.AfterAuthentication, mark("AfterAuthentication")),
+		plugger.WithTagMiddleware("greeting", mark("tag")),
+		plugger.WithOperationMiddleware("getGreeting", mark("operation")),
+		plugger.WithPhaseMiddleware(plugger.AfterRouting, mark("AfterRouting")),
+		plugger.WithMiddleware(mark("BeforeRouting")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hello", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /hello = %d", rec.Code)
+	}
+	// the phases run in the same order whatever order the options are passed in
+	want := []string{"BeforeRouting", "AfterRouting", "tag", "operation", "AfterAuthentication"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("middleware order = %v, want %v", order, want)
+	}
+}
@@ -0,0 +1,401 @@
+package plugger
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/go-openapi/spec"
+)
+
+// PreferHeader selects the mocked response status, e.g. "Prefer: code=404"
+const PreferHeader = "Prefer"
+
+// maxMockDepth limits the nesting of generated values for recursive schemas
+const maxMockDepth = 8
+
+// WithMockResponses serves responses synthesized from the spec
+// for the operations that aren't implemented, see Plug.Unimplemented
+//
+// The response body is taken from the response examples,
+// or generated from the response schema using the schema
+// examples, defaults, enums and formats.
+//
+// The first 2xx response declared for the operation is served by default.
+// Clients can select another one with the Prefer request header,
+// e.g. "Prefer: code=404".
+func WithMockResponses() Option {
+	return newOptionPlug(func(p *Plug) error {
+		if _, err := specOf(p.apiv); err != nil {
+			return err
+		}
+		p.mockResponses = true
+		return nil
+	})
+}
+
+// installMocks sets mock handlers to the unimplemented operations
+// and returns a function that restores the original handlers.
+//
+// go-swagger copies handlers to its handler cache, so the mocks are
+// only needed while the API is built, and the API itself keeps its handlers.
+func (p *Plug) installMocks() (restore func()) {
+	restore = func() {}
+	if !p.mockResponses {
+		return
+	}
+
+	doc, err := specOf(p.apiv)
+	if err != nil {
+		return
+	}
+	ops, err := specOperations(p.apiv)
+	if err != nil {
+		return
+	}
+
+	api := reflect.Indirect(p.apiv)
+	var restores []func()
+	for _, op := range ops {
+		key, ok := handlerField(p.apiv, op)
+		if !ok {
+			continue
+		}
+		if !p.isDefaultHandler(key) {
+			continue
+		}
+		field := api.FieldByName(key)
+		// a nil handler has no type to mock, the API validation reports it
+		funcType, ok := handlerFuncType(field)
+		if !ok {
+			continue
+		}
+
+		saved := reflect.New(field.Type()).Elem()
+		saved.Set(field)
+		restores = append(restores, func() { field.Set(saved) })
+
+		m := &mockOperation{root: doc.Spec(), op: op}
+		field.Set(reflect.MakeFunc(funcType, m.handle))
+	}
+
+	return func() {
+		for _, f := range restores {
+			f()
+		}
+	}
+}
+
+// mockOperation serves mocked responses of an operation
+type mockOperation struct {
+	root *spec.Swagger
+	op   specOperation
+}
+
+// handle implements the generated handler func signature
+func (m *mockOperation) handle(args []reflect.Value) []reflect.Value {
+	var r *http.Request
+	if len(args) > 0 {
+		params := reflect.Indirect(args[0])
+		if params.Kind() == reflect.Struct {
+			if f := params.FieldByName("HTTPRequest"); f.IsValid() {
+				r, _ = f.Interface().(*http.Request)
+			}
+		}
+	}
+
+	out := reflect.New(reflect.TypeOf((*middleware.Responder)(nil)).Elem()).Elem()
+	out.Set(reflect.ValueOf(m.respond(r)))
+	return []reflect.Value{out}
+}
+
+// respond creates a response preferred by the request
+func (m *mockOperation) respond(r *http.Request) middleware.Responder {
+	code, resp := m.response(preferredCode(r))
+
+	body := m.example(resp)
+	if body == nil && resp.Schema != nil {
+		body = mockValue(m.root, resp.Schema, 0)
+	}
+
+	return middleware.ResponderFunc(func(rw http.ResponseWriter, producer runtime.Producer) {
+		for name, h := range resp.Headers {
+			if v := mockHeader(h); v != "" {
+				rw.Header().Set(name, v)
+			}
+		}
+
+		rw.WriteHeader(code)
+		if body == nil {
+			return
+		}
+		if err := producer.Produce(rw, body); err != nil {
+			panic(err) // let the recovery middleware deal with this
+		}
+	})
+}
+
+// response finds the declared response with the code,
+// or the first successful one if code is 0
+func (m *mockOperation) response(code int) (int, spec.Response) {
+	responses := m.op.Responses
+	if responses == nil {
+		return http.StatusOK, spec.Response{}
+	}
+
+	if code != 0 {
+		if resp, ok := responses.StatusCodeResponses[code]; ok {
+			return code, m.resolve(resp)
+		}
+		if responses.Default != nil {
+			return code, m.resolve(*responses.Default)
+		}
+	}
+
+	codes := make([]int, 0, len(responses.StatusCodeResponses))
+	for c := range responses.StatusCodeResponses {
+		codes = append(codes, c)
+	}
+	sort.Ints(codes)
+	for _, c := range codes {
+		if c >= 200 && c < 300 {
+			return c, m.resolve(responses.StatusCodeResponses[c])
+		}
+	}
+	if len(codes) > 0 {
+		return codes[0], m.resolve(responses.StatusCodeResponses[codes[0]])
+	}
+	if responses.Default != nil {
+		return http.StatusOK, m.resolve(*responses.Default)
+	}
+	return http.StatusOK, spec.Response{}
+}
+
+func (m *mockOperation) resolve(resp spec.Response) spec.Response {
+	if resp.Ref.String() == "" {
+		return resp
+	}
+	resolved, err := spec.ResolveResponse(m.root, resp.Ref)
+	if err != nil {
+		return spec.Response{}
+	}
+	return *resolved
+}
+
+// example returns the response example of the media types the operation produces
+func (m *mockOperation) example(resp spec.Response) interface{} {
+	if len(resp.Examples) == 0 {
+		return nil
+	}
+
+	produces := m.op.Produces
+	if produces == nil {
+		produces = m.root.Produces
+	}
+	for _, mediaType := range produces {
+		if ex, ok := resp.Examples[mediaType]; ok {
+			return ex
+		}
+	}
+
+	mediaTypes := make([]string, 0, len(resp.Examples))
+	for mediaType := range resp.Examples {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Strings(mediaTypes)
+	return resp.Examples[mediaTypes[0]]
+}
+
+// preferredCode parses the status code from the Prefer request header
+func preferredCode(r *http.Request) int {
+	if r == nil {
+		return 0
+	}
+	for _, header := range r.Header[PreferHeader] {
+		for _, pref := range strings.FieldsFunc(header, func(c rune) bool { return c == ',' || c == ';' }) {
+			kv := strings.SplitN(strings.TrimSpace(pref), "=", 2)
+			if len(kv) != 2 || !strings.EqualFold(kv[0], "code") {
+				continue
+			}
+			if code, err := strconv.Atoi(strings.Trim(kv[1], `"`)); err == nil {
+				return code
+			}
+		}
+	}
+	return 0
+}
+
+// mockHeader formats a value of the response header
+func mockHeader(h spec.Header) string {
+	var v interface{}
+	switch {
+	case h.Example != nil:
+		v = h.Example
+	case h.Default != nil:
+		v = h.Default
+	case len(h.Enum) > 0:
+		v = h.Enum[0]
+	default:
+		v = mockSimple(h.Type, h.Format, h.Minimum, h.Maximum, h.MinLength)
+	}
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// mockValue generates a value matching the schema
+func mockValue(root *spec.Swagger, s *spec.Schema, depth int) interface{} {
+	if s == nil || depth > maxMockDepth {
+		return nil
+	}
+
+	if s.Ref.String() != "" {
+		resolved, err := spec.ResolveRef(root, &s.Ref)
+		if err != nil {
+			return nil
+		}
+		return mockValue(root, resolved, depth+1)
+	}
+
+	switch {
+	case s.Example != nil:
+		return s.Example
+	case s.Default != nil:
+		return s.Default
+	case len(s.Enum) > 0:
+		return s.Enum[0]
+	}
+
+	typ := ""
+	if len(s.Type) > 0 {
+		typ = s.Type[0]
+	}
+	if typ == "" {
+		switch {
+		case len(s.Properties) > 0 || len(s.AllOf) > 0:
+			typ = "object"
+		case s.Items != nil:
+			typ = "array"
+		}
+	}
+
+	switch typ {
+	case "object":
+		obj := make(map[string]interface{})
+		for i := range s.AllOf {
+			if sub, ok := mockValue(root, &s.AllOf[i], depth+1).(map[string]interface{}); ok {
+				for k, v := range sub {
+					obj[k] = v
+				}
+			}
+		}
+		for name, prop := range s.Properties {
+			prop := prop
+			if v := mockValue(root, &prop, depth+1); v != nil {
+				obj[name] = v
+			}
+		}
+		return obj
+	case "array":
+		items := []interface{}{}
+		if s.Items == nil || s.Items.Schema == nil {
+			return items
+		}
+		n := 1
+		if s.MinItems != nil && *s.MinItems > 1 {
+			n = int(*s.MinItems)
+		}
+		for i := 0; i < n; i++ {
+			if v := mockValue(root, s.Items.Schema, depth+1); v != nil {
+				items = append(items, v)
+			}
+		}
+		return items
+	default:
+		return mockSimple(typ, s.Format, s.Minimum, s.Maximum, s.MinLength)
+	}
+}
+
+// mockSimple generates a value of a simple type
+func mockSimple(typ, format string, min, max *float64, minLength *int64) interface{} {
+	switch typ {
+	case "string":
+		s := mockString(format)
+		if minLength != nil && int64(len(s)) < *minLength {
+			s += strings.Repeat("x", int(*minLength)-len(s))
+		}
+		return s
+	case "integer":
+		return int64(mockNumber(min, max))
+	case "number":
+		return mockNumber(min, max)
+	case "boolean":
+		return true
+	default:
+		return nil
+	}
+}
+
+// mockString generates a plausible string of the format
+func mockString(format string) string {
+	now := time.Now().UTC()
+
+	switch format {
+	case "date-time":
+		return now.Format(time.RFC3339)
+	case "date":
+		return now.Format("2006-01-02")
+	case "duration":
+		return "1s"
+	case "uuid", "uuid3", "uuid4", "uuid5":
+		return mockUUID()
+	case "email":
+		return "user@example.com"
+	case "hostname":
+		return "example.com"
+	case "uri", "url":
+		return "https://example.com"
+	case "ipv4":
+		return "192.0.2.1"
+	case "ipv6":
+		return "2001:db8::1"
+	case "mac":
+		return "00:00:5e:00:53:01"
+	case "byte":
+		return base64.StdEncoding.EncodeToString([]byte("string"))
+	case "password":
+		return "********"
+	default:
+		return "string"
+	}
+}
+
+// mockNumber returns a number in the range
+func mockNumber(min, max *float64) float64 {
+	var v float64
+	if min != nil && v < *min {
+		v = *min
+	}
+	if max != nil && v > *max {
+		v = *max
+	}
+	return v
+}
+
+// mockUUID generates a random version 4 UUID
+func mockUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
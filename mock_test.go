@@ -0,0 +1,92 @@
+package plugger_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-openapi/loads"
+	"github.com/go-openapi/runtime"
+
+	"github.com/ilyakaznacheev/go-plugger"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi/operations"
+	"github.com/ilyakaznacheev/go-plugger/plugtest"
+)
+
+const mockSpec = `{
+	"swagger": "2.0",
+	"info": {"title": "pets", "version": "1.0.0"},
+	"produces": ["application/json"],
+	"paths": {"/hello": {"get": {
+		"operationId": "getGreeting",
+		"responses": {
+			"200": {"description": "a pet", "schema": {"$ref": "#/definitions/Pet"}},
+			"404": {"description": "not found", "examples": {"application/json": {"message": "not here"}}}
+		}
+	}}},
+	"definitions": {"Pet": {
+		"type": "object",
+		"required": ["id"],
+		"properties": {
+			"id": {"type": "integer", "minimum": 5},
+			"uid": {"type": "string", "format": "uuid"},
+			"tags": {"type": "array", "items": {"type": "string", "enum": ["a", "b"]}},
+			"parent": {"$ref": "#/definitions/Pet"}
+		}
+	}}
+}`
+
+func TestMockResponses(t *testing.T) {
+	doc, err := loads.Analyzed(json.RawMessage(mockSpec), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := operations.NewGreetingServerAPI(doc)
+	api.RegisterProducer(runtime.JSONMime, runtime.JSONProducer())
+	srv := plugtest.New(t, api, plugger.WithMockResponses())
+
+	var pet struct {
+		ID   int      `json:"id"`
+		Tags []string `json:"tags"`
+	}
+	srv.Call("getGreeting", nil).ExpectStatus(http.StatusOK).Decode(&pet)
+	if pet.ID < 5 {
+		t.Errorf("id = %d, want at least 5", pet.ID)
+	}
+	if len(pet.Tags) != 1 || pet.Tags[0] != "a" {
+		t.Errorf("tags = %v, want [a]", pet.Tags)
+	}
+
+	req, err := srv.NewRequest("getGreeting", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(plugger.PreferHeader, "code=404")
+	var nf struct {
+		Message string `json:"message"`
+	}
+	srv.Do(req).ExpectStatus(http.StatusNotFound).Decode(&nf)
+	if nf.Message != "not here" {
+		t.Errorf("message = %q, want the example", nf.Message)
+	}
+}
+
+func TestMockResponsesKeepConfiguredHandlers(t *testing.T) {
+	api := newGreetingAPI(t)
+	configureAPI(api)
+	srv := plugtest.New(t, api, plugger.WithMockResponses())
+
+	resp := srv.Call("getGreeting", nil).ExpectStatus(http.StatusOK)
+	if got := string(resp.Payload); got != "Hello, World!" {
+		t.Errorf("payload = %q, want the configured handler response", got)
+	}
+}
+
+func TestMockResponsesGenerated(t *testing.T) {
+	srv := plugtest.New(t, newGreetingAPI(t), plugger.WithMockResponses())
+
+	resp := srv.Call("getGreeting", nil).ExpectStatus(http.StatusOK)
+	if got := string(resp.Payload); got != "string" {
+		t.Errorf("payload = %q, want a mocked string", got)
+	}
+}
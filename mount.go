@@ -0,0 +1,91 @@
+package plugger
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// MountAPI mounts one more go-swagger API to the plug
+//
+// The API is served under the prefix instead of its spec basePath,
+// so a request to <prefix>/hello is served as <basePath>/hello.
+// Options are applied to the mounted API only, so it may have its own
+// authenticators, error handler, etc. Server options are not allowed,
+// because all APIs are served by the plug server.
+//
+// The API shutdown hooks are called together with the hooks of the main API.
+// MountAPI must be called before the plug is served, and returns an error
+// if the prefix is already used by another mounted API or an endpoint.
+func (p *Plug) MountAPI(prefix string, api API, opts ...Option) error {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if !strings.HasPrefix(prefix, "/") {
+		return fmt.Errorf("plugger: mount prefix %q must start with / and must not be the root", prefix)
+	}
+	if isNil(api) {
+		return &ConfigError{Errors: []error{ErrNilAPI}}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.done != nil {
+		return ErrAlreadyServing
+	}
+	if err := p.checkMountPrefix(prefix); err != nil {
+		return err
+	}
+
+	sub, err := newPlug(nil, api, opts, false)
+	if err != nil {
+		return err
+	}
+	sub.basePath = prefix
+	// requests to the mounted API are traced by the plug middleware
+	sub.tracing = sub.tracing || p.tracing
+
+	p.r.Mount(prefix, sub.r)
+	p.mounts = append(p.mounts, sub)
+	return nil
+}
+
+// checkMountPrefix checks that the prefix isn't routed to
+// another mounted API or an endpoint yet
+func (p *Plug) checkMountPrefix(prefix string) error {
+	for _, sub := range p.mounts {
+		if sub.basePath == prefix {
+			return fmt.Errorf("plugger: an API is already mounted to %q", prefix)
+		}
+	}
+
+	for _, e := range p.endpoints {
+		if e.admin {
+			continue
+		}
+		path := strings.TrimSuffix(e.path, "/*")
+		wildcard := path != e.path
+		if path == prefix || strings.HasPrefix(path, prefix+"/") ||
+			wildcard && strings.HasPrefix(prefix, path+"/") {
+			return fmt.Errorf("plugger: mount prefix %q clashes with endpoint %q", prefix, e.path)
+		}
+	}
+	return nil
+}
+
+// buildAll builds handlers of the main and the mounted APIs
+func (p *Plug) buildAll() {
+	p.current()
+	for _, sub := range p.mounts {
+		sub.buildAll()
+	}
+}
+
+// mountedAPIs returns values of all mounted APIs
+func (p *Plug) mountedAPIs() []reflect.Value {
+	var apis []reflect.Value
+	for _, sub := range p.mounts {
+		apis = append(apis, sub.apiv)
+		apis = append(apis, sub.mountedAPIs()...)
+	}
+	return apis
+}
@@ -0,0 +1,33 @@
+package plugger_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ilyakaznacheev/go-plugger"
+)
+
+func TestMountAPI(t *testing.T) {
+	p, err := plugger.NewAPIPlug(newGreetingAPI(t), plugger.WithMetrics("/metrics"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := newGreetingAPI(t)
+	configureAPI(api)
+	if err := p.MountAPI("/v2", api); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/hello?name=Bob", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "Hello, Bob!" {
+		t.Errorf("GET /v2/hello = %d %q, want the mounted API response", rec.Code, rec.Body)
+	}
+
+	for _, prefix := range []string{"/v2", "/v2/", "/metrics"} {
+		if err := p.MountAPI(prefix, newGreetingAPI(t)); err == nil {
+			t.Errorf("MountAPI(%q) = nil, want an error", prefix)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package plugger
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+type operationKey struct{}
+
+// Operation describes the API operation a request is routed to
+type Operation struct {
+	// ID is the operationId
+	ID string
+	// Method is the HTTP method of the operation
+	Method string
+	// PathPattern is the path template the request matched,
+	// e.g. /api/users/{id}. It starts with the base path the API is served under
+	PathPattern string
+	// Tags are the operation tags
+	Tags []string
+	// Security contains the security requirements applied to the operation.
+	// Each requirement maps security scheme names to required scopes
+	Security []map[string][]string
+	// Extensions are the operation spec extensions (x-* fields)
+	Extensions spec.Extensions
+}
+
+// OperationFrom returns the API operation the request is routed to
+//
+// The operation is resolved before the plug router middleware run,
+// so it is available in any middleware added with WithMiddleware
+// or Router().Use. It returns false if the request doesn't match
+// any API operation.
+func OperationFrom(ctx context.Context) (*Operation, bool) {
+	op, ok := ctx.Value(operationKey{}).(*Operation)
+	return op, ok
+}
+
+// OperationID returns the operationId of the API operation
+// the request is routed to, or an empty string
+func OperationID(ctx context.Context) string {
+	if op, ok := OperationFrom(ctx); ok {
+		return op.ID
+	}
+	return ""
+}
+
+// resolveOperation puts the API operation into the request context
+func (p *Plug) resolveOperation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := OperationFrom(r.Context()); !ok {
+			if op, ok := p.lookupOperation(r); ok {
+				r = r.WithContext(context.WithValue(r.Context(), operationKey{}, op))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// lookupOperation finds the operation of the main or one of the mounted APIs
+func (p *Plug) lookupOperation(r *http.Request) (*Operation, bool) {
+	for _, sub := range p.mounts {
+		if _, ok := trimPathPrefix(r.URL.Path, sub.basePath); ok {
+			return sub.lookupOperation(r)
+		}
+	}
+
+	b := p.current()
+	apiReq, ok := p.apiRequest(r, b)
+	if !ok {
+		return nil, false
+	}
+
+	route, ok := b.ctx.LookupRoute(apiReq)
+	if !ok || route.Operation == nil {
+		return nil, false
+	}
+
+	base := route.BasePath
+	if p.basePath != "" {
+		base = p.basePath
+	}
+
+	op := &Operation{
+		ID:          route.Operation.ID,
+		Method:      strings.ToUpper(r.Method),
+		PathPattern: path.Join("/", base, strings.TrimPrefix(route.PathPattern, route.BasePath)),
+		Tags:        route.Operation.Tags,
+		Extensions:  route.Operation.Extensions,
+	}
+	for _, auth := range route.Authenticators {
+		req := make(map[string][]string, len(auth.Schemes))
+		for _, scheme := range auth.Schemes {
+			req[scheme] = auth.Scopes[scheme]
+		}
+		op.Security = append(op.Security, req)
+	}
+	return op, true
+}
+
+// Operations returns the API operations sorted by path and method
+//
+// PathPattern of the operations starts with the base path the API is served under.
+// Operations of the APIs mounted with MountAPI are not included.
+func (p *Plug) Operations() ([]Operation, error) {
+	doc, err := specOf(p.apiv)
+	if err != nil {
+		return nil, err
+	}
+	specOps, err := specOperations(p.apiv)
+	if err != nil {
+		return nil, err
+	}
+
+	base := doc.BasePath()
+	if p.basePath != "" {
+		base = p.basePath
+	}
+
+	ops := make([]Operation, 0, len(specOps))
+	for _, op := range specOps {
+		security := op.Security
+		if security == nil {
+			security = doc.Spec().Security
+		}
+		ops = append(ops, Operation{
+			ID:          op.ID,
+			Method:      op.Method,
+			PathPattern: path.Join("/", base, op.Path),
+			Tags:        op.Tags,
+			Security:    security,
+			Extensions:  op.Extensions,
+		})
+	}
+	return ops, nil
+}
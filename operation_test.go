@@ -0,0 +1,124 @@
+package plugger_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/go-openapi/spec"
+
+	"github.com/ilyakaznacheev/go-plugger"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi/operations"
+)
+
+// annotatedGreetingAPI creates the example API with the operation
+// tagged, secured and extended
+func annotatedGreetingAPI(t *testing.T) *operations.GreetingServerAPI {
+	t.Helper()
+
+	return editedGreetingAPI(t, func(doc, op map[string]interface{}) {
+		doc["securityDefinitions"] = map[string]interface{}{
+			"key":   map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-Key"},
+			"oauth": map[string]interface{}{"type": "oauth2", "flow": "application", "tokenUrl": "https://example.com/token"},
+		}
+		op["tags"] = []string{"greeting", "public"}
+		op["security"] = []map[string][]string{{"key": {}}, {"oauth": {"read"}}}
+		op["x-rate-limit"] = 10
+	})
+}
+
+// captureOperation returns the middleware that stores the request operation
+func captureOperation(op **plugger.Operation) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*op, _ = plugger.OperationFrom(r.Context())
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestOperationFrom(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []plugger.Option
+		url     string
+		pattern string
+	}{
+		{"without base path", nil, "/hello", "/hello"},
+		{"WithBasePath", []plugger.Option{plugger.WithBasePath("/v1")}, "/v1/hello", "/v1/hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *plugger.Operation
+			p, err := plugger.NewAPIPlug(annotatedGreetingAPI(t),
+				append(tt.opts, plugger.WithMiddleware(captureOperation(&got)))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the operation is resolved even if the request is not authenticated
+			p.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if got == nil {
+				t.Fatalf("GET %s has no operation", tt.url)
+			}
+			want := &plugger.Operation{
+				ID:          "getGreeting",
+				Method:      http.MethodGet,
+				PathPattern: tt.pattern,
+				Tags:        []string{"greeting", "public"},
+				Security:    []map[string][]string{{"key": {}}, {"oauth": {"read"}}},
+				Extensions:  spec.Extensions{"x-rate-limit": float64(10)},
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("operation = %+v, want %+v", got, want)
+			}
+			if id := plugger.OperationID(httptest.NewRequest(http.MethodGet, tt.url, nil).Context()); id != "" {
+				t.Errorf("OperationID() = %q for a request that isn't served", id)
+			}
+		})
+	}
+}
+
+func TestOperationFromUnknownRoute(t *testing.T) {
+	var got *plugger.Operation
+	p, err := plugger.NewAPIPlug(annotatedGreetingAPI(t), plugger.WithMiddleware(captureOperation(&got)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/farewell", nil),
+		httptest.NewRequest(http.MethodPost, "/hello", nil),
+	} {
+		got = nil
+		p.Handler().ServeHTTP(httptest.NewRecorder(), r)
+		if got != nil {
+			t.Errorf("%s %s has operation %+v", r.Method, r.URL.Path, got)
+		}
+	}
+}
+
+func TestOperationFromMountedAPI(t *testing.T) {
+	var got *plugger.Operation
+	p, err := plugger.NewAPIPlug(newGreetingAPI(t), plugger.WithMiddleware(captureOperation(&got)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.MountAPI("/v2", annotatedGreetingAPI(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	p.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v2/hello", nil))
+	if got == nil {
+		t.Fatal("GET /v2/hello has no operation")
+	}
+	if got.ID != "getGreeting" || got.PathPattern != "/v2/hello" {
+		t.Errorf("operation = %s %s, want getGreeting /v2/hello", got.ID, got.PathPattern)
+	}
+	// the metadata comes from the spec of the mounted API
+	if !reflect.DeepEqual(got.Tags, []string{"greeting", "public"}) || len(got.Security) != 2 {
+		t.Errorf("operation = %+v, want the mounted API metadata", got)
+	}
+}
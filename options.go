@@ -1,9 +1,10 @@
 package plugger
 
 import (
+	"fmt"
 	"io"
 	"net/http"
-	"reflect"
+	"strings"
 	"time"
 
 	"github.com/docker/go-units"
@@ -15,41 +16,64 @@ import (
 	"github.com/jessevdk/go-flags"
 )
 
-// Option is a parameter option that defines a server parameter
+// target defines what an option is applied to
+type target int
+
+// options are applied target by target in this order
+const (
+	// targetServer options set fields of the generated server
+	targetServer target = iota
+	// targetAPI options set fields of the generated API
+	targetAPI
+	// targetPlug options configure the plug itself
+	targetPlug
+	// targetRouter options configure the built-in router
+	targetRouter
+
+	targetCount
+)
+
+// Option is a parameter option that defines a server, API, plug or router parameter
 type Option interface {
-	// applyAPI will set any swagger API parameters before server setup
-	applyAPI(*Plug)
-	// applyServer will set any swagger server parameters after server setup
-	applyServer(*Plug)
+	// target tells what the option is applied to
+	target() target
+	// apply sets the option
+	apply(*Plug) error
 }
 
 type funcOption struct {
-	api func(*Plug)
-	srv func(*Plug)
+	t target
+	f func(*Plug) error
 }
 
-func (fo *funcOption) applyAPI(do *Plug) {
-	fo.api(do)
+func (fo *funcOption) target() target {
+	return fo.t
 }
 
-func (fo *funcOption) applyServer(do *Plug) {
-	fo.srv(do)
+func (fo *funcOption) apply(p *Plug) error {
+	return fo.f(p)
 }
 
-func dummyFunc(*Plug) {}
+// newOptionServer creates an option applied after the server is bound to the API
+func newOptionServer(f func(*Plug) error) *funcOption {
+	return &funcOption{t: targetServer, f: f}
+}
 
-func newOptionAPI(f func(*Plug)) *funcOption {
-	return &funcOption{
-		api: f,
-		srv: dummyFunc,
-	}
+// newOptionAPI creates an option applied after the server options,
+// so API fields set by the generated configureAPI can be overridden
+func newOptionAPI(f func(*Plug) error) *funcOption {
+	return &funcOption{t: targetAPI, f: f}
 }
 
-func newOptionServer(f func(*Plug)) *funcOption {
-	return &funcOption{
-		srv: f,
-		api: dummyFunc,
-	}
+// newOptionPlug creates an option that configures the plug
+func newOptionPlug(f func(*Plug) error) *funcOption {
+	return &funcOption{t: targetPlug, f: f}
+}
+
+// newOptionRouter creates an option applied to the router
+// before the API is mounted to it
+func newOptionRouter(f func(*Plug) error) *funcOption {
+	return &funcOption{t: targetRouter, f: f}
 }
 
 // WithConfiguredAPI runs generated API configuration function of swagger server.
@@ -60,26 +84,37 @@ func newOptionServer(f func(*Plug)) *funcOption {
 // But you don't need it if you set up the server configuration yourself
 // using the Plugger
 func WithConfiguredAPI() Option {
-	return newOptionServer(func(p *Plug) {
+	return newOptionServer(func(p *Plug) error {
 		p.s.ConfigureAPI()
+		return nil
 	})
 }
 
-func setDynParam(srvVal reflect.Value, key string, value interface{}) {
-	if rHost := reflect.Indirect(srvVal).FieldByName(key); rHost.CanSet() {
-		rHost.Set(reflect.ValueOf(value))
-	}
-}
-
 func newParamServerOption(key string, value interface{}) *funcOption {
-	return newOptionServer(func(p *Plug) {
-		setDynParam(p.sv, key, value)
+	return newOptionServer(func(p *Plug) error {
+		return setDynParam(p.sv, key, value)
 	})
 }
 
 func newParamAPIOption(key string, value interface{}) *funcOption {
-	return newOptionAPI(func(p *Plug) {
-		setDynParam(p.apiv, key, value)
+	return newOptionAPI(func(p *Plug) error {
+		return setDynParam(p.apiv, key, value)
+	})
+}
+
+// WithBasePath serves the API under the prefix instead of the spec basePath
+//
+// Use it if the plug handler is mounted to a path
+// other than the basePath defined in the swagger.yml.
+// E.g. with WithBasePath("/v2") and basePath: /api
+// a request to /v2/hello is served as /api/hello.
+func WithBasePath(prefix string) Option {
+	return newOptionPlug(func(p *Plug) error {
+		if !strings.HasPrefix(prefix, "/") {
+			return fmt.Errorf("plugger: base path %q must start with /", prefix)
+		}
+		p.basePath = prefix
+		return nil
 	})
 }
 
@@ -157,14 +192,14 @@ func WithTLSCertificate(cert string) Option {
 	return newParamServerOption("TLSCertificate", flags.Filename(cert))
 }
 
-// WithTLSCertificateKeyTLSCertificateKey the private key to use for secure connections
+// WithTLSCertificateKey the private key to use for secure connections
 func WithTLSCertificateKey(cert string) Option {
-	return newParamServerOption("TLSCertificateKey", cert)
+	return newParamServerOption("TLSCertificateKey", flags.Filename(cert))
 }
 
 // WithTLSCACertificate the certificate authority file to be used with mutual tls auth
 func WithTLSCACertificate(cert string) Option {
-	return newParamServerOption("TLSCACertificate", cert)
+	return newParamServerOption("TLSCACertificate", flags.Filename(cert))
 }
 
 // WithTLSListenLimit limit the number of outstanding requests
@@ -192,100 +227,108 @@ func WithTLSWriteTimeout(t time.Duration) Option {
 // It has a default implementation in the security package, however you can replace it for your particular usage.
 func WithBasicAuthenticator(
 	f func(security.UserPassAuthentication) runtime.Authenticator) Option {
-	return newParamServerOption("BasicAuthenticator", f)
+	return newParamAPIOption("BasicAuthenticator", f)
 }
 
 // WithAPIKeyAuthenticator generates a runtime.Authenticator from the supplied token auth function.
 // It has a default implementation in the security package, however you can replace it for your particular usage.
 func WithAPIKeyAuthenticator(
 	f func(string, string, security.TokenAuthentication) runtime.Authenticator) Option {
-	return newParamServerOption("APIKeyAuthenticator", f)
+	return newParamAPIOption("APIKeyAuthenticator", f)
 }
 
 // WithBearerAuthenticator BearerAuthenticator generates a runtime.Authenticator from the supplied bearer token auth function.
 // It has a default implementation in the security package, however you can replace it for your particular usage.
 func WithBearerAuthenticator(
 	f func(string, security.ScopedTokenAuthentication) runtime.Authenticator) Option {
-	return newParamServerOption("BearerAuthenticator", f)
+	return newParamAPIOption("BearerAuthenticator", f)
 }
 
 // WithJSONConsumer JSONConsumer registers a consumer for the following mime types:
 //   - application/json
 func WithJSONConsumer(c runtime.Consumer) Option {
-	return newParamServerOption("JSONConsumer", c)
+	return newParamAPIOption("JSONConsumer", c)
 }
 
 // WithBinProducer BinProducer registers a producer for the following mime types:
 //   - application/octet-stream
 func WithBinProducer(p runtime.Producer) Option {
-	return newParamServerOption("BinProducer", p)
+	return newParamAPIOption("BinProducer", p)
 }
 
 // WithHTMLProducer HTMLProducer registers a producer for the following mime types:
 //   - text/html
 func WithHTMLProducer(p runtime.Producer) Option {
-	return newParamServerOption("HTMLProducer", p)
+	return newParamAPIOption("HTMLProducer", p)
 }
 
 // WithJSONProducer JSONProducer registers a producer for the following mime types:
 //   - application/json
 func WithJSONProducer(p runtime.Producer) Option {
-	return newParamServerOption("JSONProducer", p)
+	return newParamAPIOption("JSONProducer", p)
 }
 
 // WithServeError ServeError is called when an error is received, there is a default handler
 // but you can set your own with this
 func WithServeError(f func(http.ResponseWriter, *http.Request, error)) Option {
-	return newParamServerOption("ServeError", f)
+	return newParamAPIOption("ServeError", f)
 }
 
 // WithPreServerShutdown PreServerShutdown is called before the HTTP(S) server is shutdown
 // This allows for custom functions to get executed before the HTTP(S) server stops accepting traffic
 func WithPreServerShutdown(f func()) Option {
-	return newParamServerOption("PreServerShutdown", f)
+	return newParamAPIOption("PreServerShutdown", f)
 }
 
 // WithServerShutdown ServerShutdown is called when the HTTP(S) server is shut down and done
 // handling all active connections and does not accept connections any more
 func WithServerShutdown(f func()) Option {
-	return newParamServerOption("ServerShutdown", f)
+	return newParamAPIOption("ServerShutdown", f)
 }
 
 // WithCommandLineOptionsGroups Custom command line argument groups with their descriptions
 func WithCommandLineOptionsGroups(g []swag.CommandLineOptionsGroup) Option {
-	return newParamServerOption("CommandLineOptionsGroups", g)
+	return newParamAPIOption("CommandLineOptionsGroups", g)
 }
 
 // WithLogger User defined logger function
 func WithLogger(f func(string, ...interface{})) Option {
-	return newParamServerOption("Logger", f)
+	return newParamAPIOption("Logger", f)
 }
 
 // WithAPIDefaults sets default values to API fields
+//
+// Fields the API doesn't have are skipped.
 func WithAPIDefaults() Option {
-	return newOptionAPI(func(p *Plug) {
-		setDynParam(p.apiv, "BasicAuthenticator", security.BasicAuth)
-		setDynParam(p.apiv, "APIKeyAuthenticator", security.APIKeyAuth)
-		setDynParam(p.apiv, "BearerAuthenticator", security.BearerAuth)
-		setDynParam(p.apiv, "JSONConsumer", runtime.JSONConsumer())
-		setDynParam(p.apiv, "BinProducer", runtime.ByteStreamProducer())
-		setDynParam(p.apiv, "JSONProducer", runtime.JSONProducer())
-		setDynParam(p.apiv, "ServeError", errors.ServeError)
-		setDynParam(p.apiv, "HTMLProducer", runtime.ProducerFunc(func(w io.Writer, data interface{}) error {
+	return newOptionAPI(func(p *Plug) error {
+		var errs configErrors
+		errs.add(setDynDefault(p.apiv, "BasicAuthenticator", security.BasicAuth))
+		errs.add(setDynDefault(p.apiv, "APIKeyAuthenticator", security.APIKeyAuth))
+		errs.add(setDynDefault(p.apiv, "BearerAuthenticator", security.BearerAuth))
+		errs.add(setDynDefault(p.apiv, "JSONConsumer", runtime.JSONConsumer()))
+		errs.add(setDynDefault(p.apiv, "BinProducer", runtime.ByteStreamProducer()))
+		errs.add(setDynDefault(p.apiv, "JSONProducer", runtime.JSONProducer()))
+		errs.add(setDynDefault(p.apiv, "ServeError", errors.ServeError))
+		errs.add(setDynDefault(p.apiv, "HTMLProducer", runtime.ProducerFunc(func(w io.Writer, data interface{}) error {
 			return errors.NotImplemented("html producer has not yet been implemented")
-		}))
+		})))
+		return errs.err()
 	})
 }
 
 // WithServerDefaults sets default values to server fields
+//
+// Fields the server doesn't have are skipped.
 func WithServerDefaults() Option {
-	return newOptionServer(func(p *Plug) {
-		setDynParam(p.sv, "CleanupTimeout", 10*time.Second)
-		setDynParam(p.sv, "GracefulTimeout", 15*time.Second)
-		setDynParam(p.sv, "MaxHeaderSize", units.MiB)
-		setDynParam(p.sv, "SocketPath", flags.Filename("/var/run/backend-storage.sock"))
-		setDynParam(p.sv, "KeepAlive", 3*time.Minute)
-		setDynParam(p.sv, "ReadTimeout", 30*time.Second)
-		setDynParam(p.sv, "WriteTimeout", 60*time.Second)
+	return newOptionServer(func(p *Plug) error {
+		var errs configErrors
+		errs.add(setDynDefault(p.sv, "CleanupTimeout", 10*time.Second))
+		errs.add(setDynDefault(p.sv, "GracefulTimeout", 15*time.Second))
+		errs.add(setDynDefault(p.sv, "MaxHeaderSize", flagext.ByteSize(units.MiB)))
+		errs.add(setDynDefault(p.sv, "SocketPath", flags.Filename("/var/run/backend-storage.sock")))
+		errs.add(setDynDefault(p.sv, "KeepAlive", 3*time.Minute))
+		errs.add(setDynDefault(p.sv, "ReadTimeout", 30*time.Second))
+		errs.add(setDynDefault(p.sv, "WriteTimeout", 60*time.Second))
+		return errs.err()
 	})
 }
@@ -0,0 +1,186 @@
+package plugger_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/runtime/flagext"
+	"github.com/go-openapi/runtime/security"
+	"github.com/go-openapi/swag"
+	"github.com/jessevdk/go-flags"
+
+	"github.com/ilyakaznacheev/go-plugger"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi"
+)
+
+func TestServerOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		opt   plugger.Option
+		field string
+		want  interface{}
+	}{
+		{"WithPort", plugger.WithPort(8080), "Port", 8080},
+		{"WithHost", plugger.WithHost("127.0.0.1"), "Host", "127.0.0.1"},
+		{"WithEnabledListeners", plugger.WithEnabledListeners([]string{"http", "unix"}), "EnabledListeners", []string{"http", "unix"}},
+		{"WithCleanupTimeout", plugger.WithCleanupTimeout(time.Second), "CleanupTimeout", time.Second},
+		{"WithGracefulTimeout", plugger.WithGracefulTimeout(2 * time.Second), "GracefulTimeout", 2 * time.Second},
+		{"WithMaxHeaderSize", plugger.WithMaxHeaderSize(4096), "MaxHeaderSize", flagext.ByteSize(4096)},
+		{"WithSocketPath", plugger.WithSocketPath("/tmp/greeting.sock"), "SocketPath", flags.Filename("/tmp/greeting.sock")},
+		{"WithListenLimit", plugger.WithListenLimit(10), "ListenLimit", 10},
+		{"WithKeepAlive", plugger.WithKeepAlive(time.Minute), "KeepAlive", time.Minute},
+		{"WithReadTimeout", plugger.WithReadTimeout(3 * time.Second), "ReadTimeout", 3 * time.Second},
+		{"WithWriteTimeout", plugger.WithWriteTimeout(4 * time.Second), "WriteTimeout", 4 * time.Second},
+		{"WithTLSHost", plugger.WithTLSHost("127.0.0.2"), "TLSHost", "127.0.0.2"},
+		{"WithTLSPort", plugger.WithTLSPort(8443), "TLSPort", 8443},
+		{"WithTLSCertificate", plugger.WithTLSCertificate("server.crt"), "TLSCertificate", flags.Filename("server.crt")},
+		{"WithTLSCertificateKey", plugger.WithTLSCertificateKey("server.key"), "TLSCertificateKey", flags.Filename("server.key")},
+		{"WithTLSCACertificate", plugger.WithTLSCACertificate("ca.crt"), "TLSCACertificate", flags.Filename("ca.crt")},
+		{"WithTLSListenLimit", plugger.WithTLSListenLimit(20), "TLSListenLimit", 20},
+		{"WithTLSKeepAlive", plugger.WithTLSKeepAlive(2 * time.Minute), "TLSKeepAlive", 2 * time.Minute},
+		{"WithTLSReadTimeout", plugger.WithTLSReadTimeout(5 * time.Second), "TLSReadTimeout", 5 * time.Second},
+		{"WithTLSWriteTimeout", plugger.WithTLSWriteTimeout(6 * time.Second), "TLSWriteTimeout", 6 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := restapi.NewServer(nil)
+			if _, err := plugger.New(srv, newGreetingAPI(t), tt.opt); err != nil {
+				t.Fatal(err)
+			}
+			if got := reflect.ValueOf(srv).Elem().FieldByName(tt.field).Interface(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s = %v, want %v", tt.field, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithServerDefaults(t *testing.T) {
+	srv := restapi.NewServer(nil)
+	if _, err := plugger.New(srv, newGreetingAPI(t), plugger.WithServerDefaults()); err != nil {
+		t.Fatal(err)
+	}
+
+	if srv.CleanupTimeout != 10*time.Second || srv.GracefulTimeout != 15*time.Second ||
+		srv.KeepAlive != 3*time.Minute || srv.ReadTimeout != 30*time.Second || srv.WriteTimeout != time.Minute {
+		t.Errorf("timeouts are not set: %+v", srv)
+	}
+	if srv.MaxHeaderSize != flagext.ByteSize(1<<20) {
+		t.Errorf("MaxHeaderSize = %v, want 1MiB", srv.MaxHeaderSize)
+	}
+	if srv.SocketPath == "" {
+		t.Error("SocketPath is not set")
+	}
+}
+
+func TestServerOptionsWithoutServer(t *testing.T) {
+	_, err := plugger.NewAPIPlug(newGreetingAPI(t), plugger.WithPort(8080))
+	if !configErrorIs(err, plugger.ErrNoServer) {
+		t.Errorf("NewAPIPlug() = %v, want %v", err, plugger.ErrNoServer)
+	}
+}
+
+func TestAPIOptions(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		called []string
+	)
+	call := func(name string) {
+		mu.Lock()
+		called = append(called, name)
+		mu.Unlock()
+	}
+	groups := []swag.CommandLineOptionsGroup{{ShortDescription: "greeting", Options: &struct{}{}}}
+
+	api := newGreetingAPI(t)
+	configureAPI(api)
+	_, err := plugger.New(restapi.NewServer(nil), api,
+		plugger.WithBasicAuthenticator(func(security.UserPassAuthentication) runtime.Authenticator {
+			call("BasicAuthenticator")
+			return nil
+		}),
+		plugger.WithAPIKeyAuthenticator(func(string, string, security.TokenAuthentication) runtime.Authenticator {
+			call("APIKeyAuthenticator")
+			return nil
+		}),
+		plugger.WithBearerAuthenticator(func(string, security.ScopedTokenAuthentication) runtime.Authenticator {
+			call("BearerAuthenticator")
+			return nil
+		}),
+		plugger.WithJSONConsumer(runtime.ConsumerFunc(func(io.Reader, interface{}) error {
+			call("JSONConsumer")
+			return nil
+		})),
+		plugger.WithServeError(func(http.ResponseWriter, *http.Request, error) {
+			call("ServeError")
+		}),
+		plugger.WithPreServerShutdown(func() { call("PreServerShutdown") }),
+		plugger.WithServerShutdown(func() { call("ServerShutdown") }),
+		plugger.WithLogger(func(string, ...interface{}) { call("Logger") }),
+		plugger.WithCommandLineOptionsGroups(groups),
+		// the generated configureAPI must not override the options
+		plugger.WithConfiguredAPI(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api.BasicAuthenticator(nil)
+	api.APIKeyAuthenticator("", "", nil)
+	api.BearerAuthenticator("", nil)
+	api.JSONConsumer.Consume(nil, nil)
+	api.ServeError(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), errors.New("failed"))
+	api.PreServerShutdown()
+	api.ServerShutdown()
+	api.Logger("message")
+
+	want := []string{
+		"BasicAuthenticator", "APIKeyAuthenticator", "BearerAuthenticator", "JSONConsumer",
+		"ServeError", "PreServerShutdown", "ServerShutdown", "Logger",
+	}
+	if !reflect.DeepEqual(called, want) {
+		t.Errorf("called %v, want %v", called, want)
+	}
+	if !reflect.DeepEqual(api.CommandLineOptionsGroups, groups) {
+		t.Errorf("CommandLineOptionsGroups = %v, want %v", api.CommandLineOptionsGroups, groups)
+	}
+}
+
+func TestAPIOptionsMissingField(t *testing.T) {
+	// the example API produces text/plain only
+	for name, opt := range map[string]plugger.Option{
+		"WithBinProducer":  plugger.WithBinProducer(runtime.ByteStreamProducer()),
+		"WithHTMLProducer": plugger.WithHTMLProducer(runtime.TextProducer()),
+		"WithJSONProducer": plugger.WithJSONProducer(runtime.JSONProducer()),
+	} {
+		_, err := plugger.New(restapi.NewServer(nil), newGreetingAPI(t), opt)
+		if !configErrorIs(err, plugger.ErrFieldNotFound) {
+			t.Errorf("%s: New() = %v, want %v", name, err, plugger.ErrFieldNotFound)
+		}
+	}
+}
+
+func TestWithAPIDefaults(t *testing.T) {
+	api := newGreetingAPI(t)
+	api.BasicAuthenticator = nil
+	api.APIKeyAuthenticator = nil
+	api.BearerAuthenticator = nil
+	api.JSONConsumer = nil
+	api.ServeError = nil
+
+	// producers the API doesn't have are skipped
+	if _, err := plugger.NewAPIPlug(api, plugger.WithAPIDefaults()); err != nil {
+		t.Fatal(err)
+	}
+	if api.BasicAuthenticator == nil || api.APIKeyAuthenticator == nil || api.BearerAuthenticator == nil ||
+		api.JSONConsumer == nil || api.ServeError == nil {
+		t.Errorf("defaults are not set: %+v", api)
+	}
+}
@@ -0,0 +1,156 @@
+// Package otelbridge exports spans traced by the plugger to OpenTelemetry
+//
+// Use it with plugger.WithTracing:
+//
+//	bsp := sdktrace.NewBatchSpanProcessor(otlpExporter)
+//	plug, err := plugger.New(srv, api, plugger.WithTracing(otelbridge.New(bsp)))
+package otelbridge
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ilyakaznacheev/go-plugger"
+)
+
+const scopeName = "github.com/ilyakaznacheev/go-plugger"
+
+// Exporter passes finished plugger spans to an OpenTelemetry span processor
+//
+// Span IDs, timestamps and attributes are kept as they are,
+// so the spans continue traces of the other OpenTelemetry services.
+type Exporter struct {
+	tp       *sdktrace.TracerProvider
+	tracer   trace.Tracer
+	resource *resource.Resource
+}
+
+// Option configures the exporter
+type Option func(*Exporter)
+
+// WithResource sets the resource the spans are reported for
+func WithResource(res *resource.Resource) Option {
+	return func(e *Exporter) {
+		e.resource = res
+	}
+}
+
+// New creates an exporter that passes spans to the span processor,
+// e.g. sdktrace.NewBatchSpanProcessor
+func New(sp sdktrace.SpanProcessor, opts ...Option) *Exporter {
+	e := &Exporter{
+		resource: resource.Default(),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	// spans are recreated by the SDK with the IDs traced by the plugger,
+	// the sampling decision is already made by then
+	e.tp = sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(sp),
+		sdktrace.WithResource(e.resource),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithIDGenerator(pluggerIDs{}),
+	)
+	e.tracer = e.tp.Tracer(scopeName)
+	return e
+}
+
+// ExportSpan converts the span and passes it to the span processor.
+// Spans of not sampled traces are dropped.
+func (e *Exporter) ExportSpan(span *plugger.Span) {
+	if !span.SpanContext.Sampled {
+		return
+	}
+
+	ctx := context.WithValue(context.Background(), spanContextKey{}, span.SpanContext)
+	if span.Parent.IsValid() {
+		// the tracestate is inherited from the parent
+		parent := span.Parent
+		parent.TraceState = span.SpanContext.TraceState
+		ctx = trace.ContextWithRemoteSpanContext(ctx, spanContext(parent, true))
+	}
+
+	_, s := e.tracer.Start(ctx, span.Name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithTimestamp(span.Start),
+		trace.WithAttributes(attributes(span.Attributes)...))
+
+	// server spans are failed by 5xx statuses only
+	if span.StatusCode >= 500 {
+		var desc string
+		if span.Err != nil {
+			desc = span.Err.Error()
+		}
+		s.SetStatus(codes.Error, desc)
+	}
+	if span.Err != nil {
+		s.RecordError(span.Err, trace.WithTimestamp(span.End))
+	}
+	s.End(trace.WithTimestamp(span.End))
+}
+
+// Shutdown shuts down the span processor, flushing all pending spans
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	return e.tp.Shutdown(ctx)
+}
+
+type spanContextKey struct{}
+
+// pluggerIDs gives the SDK span the IDs of the plugger span
+type pluggerIDs struct{}
+
+func (pluggerIDs) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	sc, _ := ctx.Value(spanContextKey{}).(plugger.SpanContext)
+	return sc.TraceID, sc.SpanID
+}
+
+func (pluggerIDs) NewSpanID(ctx context.Context, _ trace.TraceID) trace.SpanID {
+	sc, _ := ctx.Value(spanContextKey{}).(plugger.SpanContext)
+	return sc.SpanID
+}
+
+func spanContext(sc plugger.SpanContext, remote bool) trace.SpanContext {
+	var flags trace.TraceFlags
+	if sc.Sampled {
+		flags = trace.FlagsSampled
+	}
+	// an invalid tracestate is dropped as the W3C spec requires
+	state, _ := trace.ParseTraceState(sc.TraceState)
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    sc.TraceID,
+		SpanID:     sc.SpanID,
+		TraceFlags: flags,
+		TraceState: state,
+		Remote:     remote,
+	})
+}
+
+func attributes(attrs map[string]interface{}) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for k, v := range attrs {
+		switch val := v.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(k, val))
+		case int:
+			kvs = append(kvs, attribute.Int(k, val))
+		case int64:
+			kvs = append(kvs, attribute.Int64(k, val))
+		case float64:
+			kvs = append(kvs, attribute.Float64(k, val))
+		case bool:
+			kvs = append(kvs, attribute.Bool(k, val))
+		default:
+			kvs = append(kvs, attribute.String(k, fmt.Sprint(val)))
+		}
+	}
+	return kvs
+}
@@ -0,0 +1,154 @@
+package otelbridge_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ilyakaznacheev/go-plugger"
+	"github.com/ilyakaznacheev/go-plugger/otelbridge"
+)
+
+// pluggerSpan returns a finished span of a sampled trace continued from a remote parent
+func pluggerSpan(t *testing.T) *plugger.Span {
+	t.Helper()
+
+	parent, err := plugger.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &plugger.Span{
+		Name: "getGreeting",
+		SpanContext: plugger.SpanContext{
+			TraceID:    parent.TraceID,
+			SpanID:     [8]byte{1, 2, 3, 4, 5, 6, 7, 8},
+			Sampled:    true,
+			TraceState: "rojo=00f067aa0ba902b7",
+		},
+		Parent: parent,
+		Start:  start,
+		End:    start.Add(time.Second),
+		Attributes: map[string]interface{}{
+			"http.method":      "GET",
+			"http.status_code": 200,
+		},
+		StatusCode: 200,
+	}
+}
+
+func TestExportSpan(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	res := resource.NewSchemaless(attribute.String("service.name", "greeting"))
+	exp := otelbridge.New(rec, otelbridge.WithResource(res))
+	span := pluggerSpan(t)
+	exp.ExportSpan(span)
+
+	ended := rec.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("exported %d spans, want 1", len(ended))
+	}
+	got := ended[0]
+
+	if got.Name() != "getGreeting" || got.SpanKind() != trace.SpanKindServer {
+		t.Errorf("span = %s %v, want a getGreeting server span", got.Name(), got.SpanKind())
+	}
+	sc := got.SpanContext()
+	if sc.TraceID() != trace.TraceID(span.SpanContext.TraceID) || sc.SpanID() != trace.SpanID(span.SpanContext.SpanID) {
+		t.Errorf("span context = %s %s, want the plugger IDs", sc.TraceID(), sc.SpanID())
+	}
+	if !sc.IsSampled() || sc.TraceState().String() != "rojo=00f067aa0ba902b7" {
+		t.Errorf("span context = %+v, want sampled with the tracestate", sc)
+	}
+	if p := got.Parent(); !p.IsRemote() || p.SpanID() != trace.SpanID(span.Parent.SpanID) {
+		t.Errorf("parent = %+v, want the remote parent", p)
+	}
+	if !got.StartTime().Equal(span.Start) || !got.EndTime().Equal(span.End) {
+		t.Errorf("span time = %v - %v, want %v - %v", got.StartTime(), got.EndTime(), span.Start, span.End)
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range got.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["http.method"].AsString() != "GET" || attrs["http.status_code"].AsInt64() != 200 {
+		t.Errorf("attributes = %v", got.Attributes())
+	}
+	if got.Status().Code != codes.Unset || len(got.Events()) != 0 {
+		t.Errorf("status = %+v, events = %v, want no error", got.Status(), got.Events())
+	}
+	if v, ok := got.Resource().Set().Value("service.name"); !ok || v.AsString() != "greeting" {
+		t.Errorf("resource = %v", got.Resource())
+	}
+	if got.InstrumentationScope().Name != "github.com/ilyakaznacheev/go-plugger" {
+		t.Errorf("scope = %+v", got.InstrumentationScope())
+	}
+}
+
+func TestExportSpanError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		want   codes.Code
+	}{
+		{"client error", 400, codes.Unset},
+		{"server error", 500, codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tracetest.NewSpanRecorder()
+			span := pluggerSpan(t)
+			span.StatusCode = tt.status
+			span.Err = errors.New("failed")
+			otelbridge.New(rec).ExportSpan(span)
+
+			got := rec.Ended()[0]
+			if got.Status().Code != tt.want {
+				t.Errorf("status = %+v, want %v", got.Status(), tt.want)
+			}
+			events := got.Events()
+			if len(events) != 1 || events[0].Name != "exception" || !events[0].Time.Equal(span.End) {
+				t.Fatalf("events = %+v, want the exception", events)
+			}
+			for _, kv := range events[0].Attributes {
+				if kv.Key == "exception.message" && kv.Value.AsString() != "failed" {
+					t.Errorf("exception.message = %q", kv.Value.AsString())
+				}
+			}
+		})
+	}
+}
+
+func TestExportSpanRoot(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	exp := otelbridge.New(rec)
+
+	span := pluggerSpan(t)
+	span.Parent = plugger.SpanContext{}
+	span.SpanContext.TraceState = ""
+	exp.ExportSpan(span)
+
+	// spans of not sampled traces are dropped
+	unsampled := pluggerSpan(t)
+	unsampled.SpanContext.Sampled = false
+	exp.ExportSpan(unsampled)
+
+	ended := rec.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("exported %d spans, want 1", len(ended))
+	}
+	if ended[0].Parent().IsValid() || ended[0].SpanContext().TraceID() != trace.TraceID(span.SpanContext.TraceID) {
+		t.Errorf("span = %+v, want a root span of the plugger trace", ended[0].SpanContext())
+	}
+
+	if err := exp.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() = %v", err)
+	}
+}
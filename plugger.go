@@ -1,7 +1,16 @@
 package plugger
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
 	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-chi/chi"
 )
@@ -15,6 +24,62 @@ type Plug struct {
 	apiv reflect.Value
 
 	r chi.Router
+
+	// APIs mounted with MountAPI
+	mounts []*Plug
+
+	// go-swagger handler is built lazily
+	buildMu  sync.Mutex
+	handler  atomic.Value
+	basePath string
+
+	// middleware attached to the go-swagger handler
+	opMiddleware     []operationMiddleware
+	routedMiddleware []func(http.Handler) http.Handler
+	authMiddleware   []func(http.Handler) http.Handler
+
+	// fail to serve if some handlers are not implemented
+	strictHandlers bool
+	// serve mocked responses for unimplemented operations
+	mockResponses bool
+
+	// operational endpoints served by the router
+	endpoints []endpoint
+	metrics   *metrics
+	admin     *adminServer
+	// record API errors for the request spans
+	tracing bool
+
+	// listeners
+	listeners map[string]net.Listener
+	addrs     map[string]net.Addr
+	bound     map[string]net.Listener
+	ready     chan struct{}
+
+	// logging
+	logger      Logger
+	noFatalExit bool
+
+	// signal handling
+	ownSignals    bool
+	signals       []os.Signal
+	shutdownDelay time.Duration
+
+	// systemd integration
+	systemd      bool
+	stoppingOnce sync.Once
+
+	// graceful restart
+	restartSignal os.Signal
+	handoff       []*handoffListener
+
+	// lifecycle state
+	stopping int32
+	mu       sync.Mutex
+	done     chan struct{}
+	serveErr error
+	fatalErr error
+	hooks    hookState
 }
 
 // NewPlug creates a new Swagger API plug
@@ -29,51 +94,271 @@ type Plug struct {
 // You can also still use the server structure to parse
 // command-line flags using "github.com/jessevdk/go-flags"
 // library as go-swagger does
+//
+// Options that can't be applied are logged and ignored.
+// NewPlug panics if the server can't be bound to the API.
+// Use New to get all configuration errors instead.
 func NewPlug(srv Server, api API, opts ...Option) *Plug {
-	// the current go-swagger version doesn't provide
-	// access to the exported fields via methods,
-	// so the only way to do so dynamically is reflection.
-	sv := reflect.ValueOf(srv)
-	apiv := reflect.ValueOf(api)
+	if err := validateServerAPI(srv, api); err != nil {
+		panic(&ConfigError{Errors: []error{err}})
+	}
+	setServerAPI(srv, api)
+
+	p, _ := newPlug(srv, api, opts, true)
+	return p
+}
+
+// New creates a new Swagger API plug
+//
+// It works like NewPlug, but validates the server and the API
+// and returns a *ConfigError listing every option that
+// can't be applied, instead of ignoring it.
+func New(srv Server, api API, opts ...Option) (*Plug, error) {
+	if err := validateServerAPI(srv, api); err != nil {
+		return nil, &ConfigError{Errors: []error{err}}
+	}
 
-	// we use Chi router to let the user set up
-	// some middleware or do anything he or she
-	// wants to do
-	r := chi.NewRouter()
-	r.Mount("/", api.Serve(nil))
+	// the server has to be bound first, because
+	// generated servers configure the API in SetAPI
+	setServerAPI(srv, api)
 
+	return newPlug(srv, api, opts, false)
+}
+
+// NewAPIPlug creates a new Swagger API plug without a server
+//
+// Use it to embed the API into an existing server,
+// router or http.ServeMux with Handler.
+// The plug can't be served by itself, and server options
+// are reported as errors.
+func NewAPIPlug(api API, opts ...Option) (*Plug, error) {
+	if isNil(api) {
+		return nil, &ConfigError{Errors: []error{ErrNilAPI}}
+	}
+	return newPlug(nil, api, opts, false)
+}
+
+// newPlug creates the plug and applies the options,
+// options that can't be applied are only logged if lenient is set
+func newPlug(srv Server, api API, opts []Option, lenient bool) (*Plug, error) {
 	p := &Plug{
-		s:    srv,
-		sv:   sv,
-		api:  api,
-		apiv: apiv,
-		r:    r,
+		s:   srv,
+		api: api,
+		// we use Chi router to let the user set up
+		// some middleware or do anything he or she
+		// wants to do
+		r:     chi.NewRouter(),
+		ready: make(chan struct{}),
 	}
 
-	// apply API options
-	for _, opt := range opts {
-		opt.applyAPI(p)
+	// the operation has to be known to all router middleware
+	p.r.Use(p.resolveOperation)
+
+	// the current go-swagger version doesn't provide
+	// access to the exported fields via methods,
+	// so the only way to do so dynamically is reflection.
+	if srv != nil {
+		p.sv = reflect.ValueOf(srv)
 	}
+	p.apiv = reflect.ValueOf(api)
 
-	setServerAPI(srv, api)
+	// apply options target by target,
+	// so the result doesn't depend on the option order
+	var errs configErrors
+	for t := target(0); t < targetCount; t++ {
+		for _, opt := range opts {
+			if opt.target() != t {
+				continue
+			}
+			if t == targetServer && srv == nil {
+				errs.add(fmt.Errorf("%w: server options can't be applied", ErrNoServer))
+				continue
+			}
+			errs.add(opt.apply(p))
+		}
+	}
 
-	// apply server options
-	for _, opt := range opts {
-		opt.applyServer(p)
+	if err := errs.err(); err != nil && !lenient {
+		return nil, err
+	}
+	for _, err := range errs {
+		p.log().Warn("option is ignored", "error", err)
 	}
 
-	return p
+	// chi doesn't allow middleware after routes,
+	// so the endpoints and the API are mounted after the router options.
+	// The handler itself is built on the first use.
+	p.routeEndpoints()
+	p.r.Mount("/", http.HandlerFunc(p.serveAPI))
+	return p, nil
 }
 
+var (
+	// ErrAlreadyServing is returned if the plug is served more than once
+	ErrAlreadyServing = errors.New("plugger: plug is already serving")
+	// ErrNoServer is returned if a plug created without a server is served
+	ErrNoServer = errors.New("plugger: plug has no server")
+)
+
 // Serve the API
+//
+// Serve blocks until the server is shut down
+// either by Shutdown or by the signal handler,
+// see WithoutSignalHandling and WithSignals.
 func (p *Plug) Serve() error {
+	return p.ServeContext(context.Background())
+}
+
+// ServeContext serves the API until the server is shut down or ctx is cancelled.
+//
+// When ctx is cancelled the server is shut down gracefully
+// and the result of the shutdown is returned, the same way Shutdown does.
+func (p *Plug) ServeContext(ctx context.Context) error {
+	done, err := p.start()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-done:
+		return p.result()
+	case <-ctx.Done():
+		return p.Shutdown(context.Background())
+	}
+}
+
+// Shutdown server and wait until all listeners are drained.
+//
+// It returns nil if the server was shut down gracefully.
+// Otherwise it returns a *ShutdownError, that contains the
+// real cause (e.g. context.DeadlineExceeded if GracefulTimeout
+// has expired) and tells which of the shutdown hooks have run.
+//
+// If ctx is done before the server has stopped,
+// Shutdown stops waiting and returns the context error.
+// The listeners are closed after the delay set by WithShutdownDelay.
+//
+// A plug created with NewAPIPlug has no server to shut down,
+// but its readiness checks start failing, see WithHealthChecks.
+func (p *Plug) Shutdown(ctx context.Context) error {
+	return p.shutdown(ctx, true)
+}
+
+// shutdown shuts the server down, after the shutdown delay if delay is set
+func (p *Plug) shutdown(ctx context.Context, delay bool) error {
+	// readiness fails from now on, even if the plug is served by another server
+	atomic.StoreInt32(&p.stopping, 1)
+	if p.s == nil {
+		return nil
+	}
+	p.notifyStopping()
+
+	p.mu.Lock()
+	done := p.done
+	p.mu.Unlock()
+
+	// the server wasn't started, nothing to wait for
+	if done == nil {
+		return p.s.Shutdown()
+	}
+
+	if delay {
+		p.delayShutdown(ctx, done)
+	}
+	if err := p.s.Shutdown(); err != nil {
+		return err
+	}
+
+	select {
+	case <-done:
+		return p.result()
+	case <-ctx.Done():
+		return &ShutdownError{
+			Err:            ctx.Err(),
+			ShutdownStatus: p.hooks.status(),
+		}
+	}
+}
+
+// ShutdownStatus reports which of the API shutdown hooks have been called
+func (p *Plug) ShutdownStatus() ShutdownStatus {
+	return p.hooks.status()
+}
+
+// start runs the server in background
+// and returns a channel that is closed when the server stops
+func (p *Plug) start() (<-chan struct{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.s == nil {
+		return nil, ErrNoServer
+	}
+	if p.done != nil {
+		return nil, ErrAlreadyServing
+	}
+	if err := p.checkHandlers(); err != nil {
+		return nil, err
+	}
+	if err := p.listen(); err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+	p.done = done
+
+	p.hooks.onPreShutdown = p.notifyStopping
+	p.hooks.install(p.apiv, p.mountedAPIs())
+	p.installLogger()
+	p.buildAll()
 	p.s.SetHandler(p.r)
-	return p.s.Serve()
+	p.stopServerSignals(done)
+	p.handleSignals(done)
+	p.notifySystemd(done)
+	p.handleRestart(done)
+	p.notifyRestarted(done)
+
+	go func() {
+		defer close(done)
+		// admin endpoints stay available while the server drains
+		if p.admin != nil {
+			defer p.admin.shutdown(p.gracefulTimeout(), p.log())
+		}
+		defer p.closeListeners()
+
+		err := p.s.Serve()
+
+		p.mu.Lock()
+		p.serveErr = err
+		p.mu.Unlock()
+	}()
+
+	return done, nil
 }
 
-// Shutdown server and clean up resources
-func (p *Plug) Shutdown() error {
-	return p.s.Shutdown()
+// result returns an error the server has stopped with
+func (p *Plug) result() error {
+	p.mu.Lock()
+	err := p.serveErr
+	if p.fatalErr != nil {
+		err = p.fatalErr
+	}
+	p.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	return p.hooks.err()
+}
+
+// Handler returns the plug as a http.Handler
+//
+// It serves the API with all the plug middleware and routes,
+// so it can be mounted to an existing server, router or http.ServeMux.
+// The request path must contain the spec basePath,
+// or the prefix set by WithBasePath.
+func (p *Plug) Handler() http.Handler {
+	p.buildAll()
+	return p.r
 }
 
 // Router returns a built-in router
@@ -87,6 +372,24 @@ func (p *Plug) Router() chi.Router {
 	return p.r
 }
 
+// validateServerAPI checks that the server can be bound to the API
+func validateServerAPI(srv Server, api API) error {
+	if isNil(srv) {
+		return ErrNilServer
+	}
+	if isNil(api) {
+		return ErrNilAPI
+	}
+
+	setAPI := reflect.ValueOf(srv).MethodByName("SetAPI")
+	if !setAPI.IsValid() ||
+		setAPI.Type().NumIn() != 1 ||
+		!reflect.TypeOf(api).AssignableTo(setAPI.Type().In(0)) {
+		return fmt.Errorf("%w: %T, %T", ErrNoSetAPI, srv, api)
+	}
+	return nil
+}
+
 // setServerAPI dynamically calls the method
 // server.SetAPI(api)
 func setServerAPI(srv Server, api API) {
@@ -96,3 +399,12 @@ func setServerAPI(srv Server, api API) {
 		MethodByName("SetAPI").
 		Call([]reflect.Value{apiVal})
 }
+
+// isNil checks if an interface is nil or holds a nil pointer
+func isNil(i interface{}) bool {
+	if i == nil {
+		return true
+	}
+	v := reflect.ValueOf(i)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
@@ -0,0 +1,187 @@
+package plugger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/loads"
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/runtime/middleware"
+
+	"github.com/ilyakaznacheev/go-plugger"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi/operations"
+)
+
+// newGreetingAPI creates the example API with the handlers generated by go-swagger
+func newGreetingAPI(t testing.TB) *operations.GreetingServerAPI {
+	t.Helper()
+
+	doc, err := loads.Embedded(restapi.SwaggerJSON, restapi.FlatSwaggerJSON)
+	if err != nil {
+		t.Fatalf("can't load the spec: %v", err)
+	}
+	api := operations.NewGreetingServerAPI(doc)
+	api.TxtProducer = runtime.TextProducer()
+	return api
+}
+
+// editedGreetingAPI creates the example API from the spec changed by edit.
+// op is the getGreeting operation of the spec
+func editedGreetingAPI(t *testing.T, edit func(spec, op map[string]interface{})) *operations.GreetingServerAPI {
+	t.Helper()
+
+	var spec map[string]interface{}
+	if err := json.Unmarshal(restapi.SwaggerJSON, &spec); err != nil {
+		t.Fatal(err)
+	}
+	edit(spec, spec["paths"].(map[string]interface{})["/hello"].(map[string]interface{})["get"].(map[string]interface{}))
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := loads.Analyzed(raw, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := operations.NewGreetingServerAPI(doc)
+	api.TxtProducer = runtime.TextProducer()
+	configureAPI(api)
+	return api
+}
+
+// configureAPI sets a real handler the way configure_*.go files do
+func configureAPI(api *operations.GreetingServerAPI) {
+	api.GetGreetingHandler = operations.GetGreetingHandlerFunc(func(params operations.GetGreetingParams) middleware.Responder {
+		name := "World"
+		if params.Name != nil {
+			name = *params.Name
+		}
+		return operations.NewGetGreetingOK().WithPayload("Hello, " + name + "!")
+	})
+}
+
+// configErrorIs reports whether any of the plug configuration errors is target
+func configErrorIs(err, target error) bool {
+	var cfgErr *plugger.ConfigError
+	if !errors.As(err, &cfgErr) {
+		return errors.Is(err, target)
+	}
+	for _, e := range cfgErr.Errors {
+		if errors.Is(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// noSetAPIServer is a server that can't be bound to an API
+type noSetAPIServer struct {
+	plugger.Server
+}
+
+// otherAPI is an API the generated server can't be bound to
+type otherAPI struct {
+	*operations.GreetingServerAPI
+}
+
+func TestNewInvalidServer(t *testing.T) {
+	tests := []struct {
+		name string
+		srv  plugger.Server
+		api  plugger.API
+		want error
+	}{
+		{"nil server", nil, newGreetingAPI(t), plugger.ErrNilServer},
+		{"nil api", restapi.NewServer(nil), nil, plugger.ErrNilAPI},
+		{"no SetAPI", noSetAPIServer{restapi.NewServer(nil)}, newGreetingAPI(t), plugger.ErrNoSetAPI},
+		{"other api", restapi.NewServer(nil), otherAPI{newGreetingAPI(t)}, plugger.ErrNoSetAPI},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := plugger.New(tt.srv, tt.api)
+			var cfgErr *plugger.ConfigError
+			if !errors.As(err, &cfgErr) || len(cfgErr.Errors) != 1 || !errors.Is(cfgErr.Errors[0], tt.want) {
+				t.Errorf("New() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewInvalidOptions(t *testing.T) {
+	_, err := plugger.New(restapi.NewServer(nil), newGreetingAPI(t),
+		plugger.WithHandler("getGreeting", func(string) {}),
+		plugger.WithJSONProducer(runtime.JSONProducer()),
+		plugger.WithBasePath("v1"),
+		plugger.WithPort(8080))
+
+	var cfgErr *plugger.ConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("New() = %v, want a *ConfigError", err)
+	}
+	if len(cfgErr.Errors) != 3 {
+		t.Fatalf("New() reports %d errors, want 3: %v", len(cfgErr.Errors), err)
+	}
+
+	var fieldErr *plugger.FieldError
+	if !errors.As(cfgErr.Errors[0], &fieldErr) || !errors.Is(fieldErr, plugger.ErrFieldType) ||
+		fieldErr.Field != "GetGreetingHandler" {
+		t.Errorf("errors[0] = %v, want %v of GetGreetingHandler", cfgErr.Errors[0], plugger.ErrFieldType)
+	}
+	if !errors.As(cfgErr.Errors[1], &fieldErr) || !errors.Is(fieldErr, plugger.ErrFieldNotFound) ||
+		fieldErr.Field != "JSONProducer" {
+		t.Errorf("errors[1] = %v, want %v of JSONProducer", cfgErr.Errors[1], plugger.ErrFieldNotFound)
+	}
+	if !strings.Contains(cfgErr.Errors[2].Error(), `"v1"`) {
+		t.Errorf("errors[2] = %v, want the base path error", cfgErr.Errors[2])
+	}
+}
+
+func TestNewPlugIgnoresInvalidOptions(t *testing.T) {
+	var logs bytes.Buffer
+	srv := restapi.NewServer(nil)
+	p := plugger.NewPlug(srv, newGreetingAPI(t),
+		plugger.WithStructuredLogger(plugger.NewStdLogger(log.New(&logs, "", 0))),
+		plugger.WithJSONProducer(runtime.JSONProducer()),
+		plugger.WithPort(8080))
+	if p == nil {
+		t.Fatal("NewPlug() = nil")
+	}
+	if srv.Port != 8080 {
+		t.Errorf("Port = %d, want the valid options applied", srv.Port)
+	}
+	if !strings.Contains(logs.String(), "option is ignored") {
+		t.Errorf("ignored option is not logged:\n%s", logs.String())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("NewPlug doesn't panic if the server can't be bound to the API")
+		}
+	}()
+	plugger.NewPlug(noSetAPIServer{restapi.NewServer(nil)}, newGreetingAPI(t))
+}
+
+// servePlug serves the plug until the test ends
+func servePlug(t *testing.T, p *plugger.Plug) {
+	t.Helper()
+
+	errc := make(chan error, 1)
+	go func() { errc <- p.Serve() }()
+	select {
+	case <-p.Ready():
+	case err := <-errc:
+		t.Fatalf("Serve() = %v", err)
+	}
+	t.Cleanup(func() {
+		p.Shutdown(context.Background())
+		<-errc
+	})
+}
@@ -0,0 +1,231 @@
+// Package plugtest provides an in-process test harness for plugged APIs
+//
+// The API is served by an httptest server, so no real port is needed:
+//
+//	func TestGreeting(t *testing.T) {
+//		srv := plugtest.New(t, api)
+//
+//		name := "Alice"
+//		resp := srv.Call("getGreeting", operations.GetGreetingParams{Name: &name})
+//		resp.ExpectStatus(http.StatusOK)
+//	}
+//
+// Every response is checked against the responses the spec declares
+// for the operation, and the test fails if it doesn't match.
+package plugtest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+
+	"github.com/ilyakaznacheev/go-plugger"
+)
+
+// Server serves a plugged API in-process
+type Server struct {
+	// Server is the test HTTP server the plug is served by
+	*httptest.Server
+	// Plug is the plug under test
+	Plug *plugger.Plug
+
+	t       testing.TB
+	spec    *spec.Swagger
+	formats strfmt.Registry
+	ops     map[string]*operation
+}
+
+// operation is an API operation with its spec definition
+type operation struct {
+	plugger.Operation
+
+	params    []spec.Parameter
+	consumes  []string
+	responses *spec.Responses
+}
+
+// New plugs the API and serves it with a test server
+//
+// The options are applied as plugger.NewAPIPlug does.
+// The test fails if the plug can't be created.
+// The server is closed when the test and its subtests complete.
+func New(t testing.TB, api plugger.API, opts ...plugger.Option) *Server {
+	t.Helper()
+
+	plug, err := plugger.NewAPIPlug(api, opts...)
+	if err != nil {
+		t.Fatalf("plugtest: can't plug the API: %v", err)
+	}
+	return newServer(t, plug, api.Formats())
+}
+
+// NewFromPlug serves an existing plug with a test server
+//
+// The plug doesn't have to be served by its own server,
+// and it isn't shut down when the test completes.
+func NewFromPlug(t testing.TB, plug *plugger.Plug) *Server {
+	t.Helper()
+	return newServer(t, plug, strfmt.Default)
+}
+
+func newServer(t testing.TB, plug *plugger.Plug, formats strfmt.Registry) *Server {
+	t.Helper()
+
+	s := &Server{
+		Plug:    plug,
+		t:       t,
+		formats: formats,
+	}
+	if err := s.loadOperations(); err != nil {
+		t.Fatalf("plugtest: %v", err)
+	}
+
+	s.Server = httptest.NewServer(plug.Handler())
+	t.Cleanup(s.Close)
+	return s
+}
+
+// loadOperations indexes the API operations by operationId
+func (s *Server) loadOperations() error {
+	doc, err := s.Plug.Spec()
+	if err != nil {
+		return err
+	}
+	ops, err := s.Plug.Operations()
+	if err != nil {
+		return err
+	}
+
+	s.spec = doc.Spec()
+	s.ops = make(map[string]*operation, len(ops))
+	for _, op := range ops {
+		s.ops[op.ID] = &operation{Operation: op}
+	}
+
+	if s.spec.Paths == nil {
+		return nil
+	}
+	for _, item := range s.spec.Paths.Paths {
+		for _, specOp := range pathOperations(item) {
+			op, ok := s.ops[specOp.ID]
+			if !ok {
+				continue
+			}
+			params, err := operationParams(s.spec, item, specOp)
+			if err != nil {
+				return fmt.Errorf("operation %s: %w", specOp.ID, err)
+			}
+			op.params = params
+			op.consumes = specOp.Consumes
+			if op.consumes == nil {
+				op.consumes = s.spec.Consumes
+			}
+			op.responses = specOp.Responses
+		}
+	}
+	return nil
+}
+
+// Call calls the operation with the params and returns the response
+//
+// params is the operation parameters struct generated by go-swagger,
+// a pointer to it, or a map of values by the parameter names from the spec.
+// Nil pointers are treated as unset parameters.
+//
+// The test fails if the request can't be made or the response
+// doesn't match the spec.
+func (s *Server) Call(operationID string, params interface{}) *Response {
+	s.t.Helper()
+
+	req, err := s.NewRequest(operationID, params)
+	if err != nil {
+		s.t.Fatalf("plugtest: %v", err)
+	}
+	return s.Do(req)
+}
+
+// NewRequest creates a request to the operation with the params
+//
+// Use it to adjust the request, e.g. to set authentication headers,
+// before it is sent with Do. See Call for the params description.
+func (s *Server) NewRequest(operationID string, params interface{}) (*http.Request, error) {
+	op, ok := s.ops[operationID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", plugger.ErrUnknownOperation, operationID)
+	}
+	req, err := newRequest(s.URL, op, params)
+	if err != nil {
+		return nil, fmt.Errorf("operation %s: %w", operationID, err)
+	}
+	return withOperation(req, op), nil
+}
+
+// Do sends the request and returns the response
+//
+// If the request was created by NewRequest,
+// the response is checked against the spec.
+func (s *Server) Do(req *http.Request) *Response {
+	s.t.Helper()
+
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		s.t.Fatalf("plugtest: %s %s: %v", req.Method, req.URL, err)
+	}
+	r := newResponse(s.t, resp)
+
+	if op, ok := operationFrom(req); ok {
+		if err := s.checkResponse(op, r); err != nil {
+			s.t.Errorf("plugtest: operation %s: %v", op.ID, err)
+		}
+	}
+	return r
+}
+
+// pathOperations returns operations of the path item
+func pathOperations(item spec.PathItem) []*spec.Operation {
+	var ops []*spec.Operation
+	for _, op := range []*spec.Operation{
+		item.Get, item.Put, item.Post, item.Delete,
+		item.Options, item.Head, item.Patch,
+	} {
+		if op != nil {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// operationParams returns parameters of the operation and its path,
+// the operation ones override the path ones
+func operationParams(sw *spec.Swagger, item spec.PathItem, op *spec.Operation) ([]spec.Parameter, error) {
+	type key struct{ in, name string }
+
+	var (
+		params []spec.Parameter
+		index  = make(map[key]int)
+	)
+	for _, list := range [][]spec.Parameter{item.Parameters, op.Parameters} {
+		for _, param := range list {
+			if param.Ref.String() != "" {
+				resolved, err := spec.ResolveParameter(sw, param.Ref)
+				if err != nil {
+					return nil, err
+				}
+				param = *resolved
+			}
+
+			k := key{param.In, param.Name}
+			if i, ok := index[k]; ok {
+				params[i] = param
+				continue
+			}
+			index[k] = len(params)
+			params = append(params, param)
+		}
+	}
+	return params, nil
+}
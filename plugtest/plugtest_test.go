@@ -0,0 +1,136 @@
+package plugtest_test
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-openapi/loads"
+	openapiruntime "github.com/go-openapi/runtime"
+	"github.com/go-openapi/runtime/middleware"
+
+	"github.com/ilyakaznacheev/go-plugger"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi/operations"
+	"github.com/ilyakaznacheev/go-plugger/plugtest"
+)
+
+// newAPI creates the example API answering with the responder
+func newAPI(t testing.TB, respond func(params operations.GetGreetingParams) middleware.Responder) *operations.GreetingServerAPI {
+	t.Helper()
+
+	doc, err := loads.Embedded(restapi.SwaggerJSON, restapi.FlatSwaggerJSON)
+	if err != nil {
+		t.Fatalf("can't load the spec: %v", err)
+	}
+	api := operations.NewGreetingServerAPI(doc)
+	api.TxtProducer = openapiruntime.TextProducer()
+	api.GetGreetingHandler = operations.GetGreetingHandlerFunc(respond)
+	return api
+}
+
+func greet(params operations.GetGreetingParams) middleware.Responder {
+	name := "World"
+	if params.Name != nil {
+		name = *params.Name
+	}
+	return operations.NewGetGreetingOK().WithPayload("Hello, " + name + "!")
+}
+
+func TestCall(t *testing.T) {
+	srv := plugtest.New(t, newAPI(t, greet), plugger.WithBasePath("/v1"))
+
+	name := "Alice"
+	for _, params := range []interface{}{
+		operations.GetGreetingParams{Name: &name},
+		&operations.GetGreetingParams{Name: &name},
+		map[string]interface{}{"name": name},
+	} {
+		resp := srv.Call("getGreeting", params).ExpectStatus(http.StatusOK)
+		if got := string(resp.Payload); got != "Hello, Alice!" {
+			t.Errorf("%T: payload = %q", params, got)
+		}
+		if got := resp.Request.URL.Path; got != "/v1/hello" {
+			t.Errorf("%T: path = %q, want /v1/hello", params, got)
+		}
+	}
+
+	resp := srv.Call("getGreeting", nil).ExpectStatus(http.StatusOK)
+	if got := string(resp.Payload); got != "Hello, World!" {
+		t.Errorf("payload = %q, want the default name", got)
+	}
+}
+
+func TestCallChecksResponses(t *testing.T) {
+	api := newAPI(t, func(operations.GetGreetingParams) middleware.Responder {
+		return middleware.Error(http.StatusTeapot, "undeclared")
+	})
+
+	ft := &fakeT{TB: t}
+	srv := plugtest.New(ft, api)
+	ft.run(func() { srv.Call("getGreeting", nil) })
+	if !ft.failed(`status 418`) {
+		t.Errorf("undeclared response isn't reported, errors: %v", ft.errors)
+	}
+
+	ft = &fakeT{TB: t}
+	srv = plugtest.New(ft, newAPI(t, greet))
+	ft.run(func() { srv.Call("noSuchOperation", nil) })
+	if !ft.failed(`unknown operation`) {
+		t.Errorf("unknown operation isn't reported, errors: %v", ft.errors)
+	}
+
+	ft = &fakeT{TB: t}
+	srv = plugtest.New(ft, newAPI(t, greet))
+	ft.run(func() { srv.Call("getGreeting", nil).ExpectStatus(http.StatusCreated) })
+	if !ft.failed(`expected status 201, got 200`) {
+		t.Errorf("unexpected status isn't reported, errors: %v", ft.errors)
+	}
+}
+
+// fakeT records the test failures instead of failing the test
+type fakeT struct {
+	testing.TB
+
+	mu     sync.Mutex
+	errors []string
+}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.mu.Lock()
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+	t.mu.Unlock()
+}
+
+func (t *fakeT) Fatalf(format string, args ...interface{}) {
+	t.Errorf(format, args...)
+	t.FailNow()
+}
+
+func (t *fakeT) FailNow() {
+	runtime.Goexit()
+}
+
+// run calls f in a goroutine, so FailNow doesn't end the test
+func (t *fakeT) run(f func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+	<-done
+}
+
+func (t *fakeT) failed(substr string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, e := range t.errors {
+		if strings.Contains(e, substr) {
+			return true
+		}
+	}
+	return false
+}
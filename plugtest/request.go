@@ -0,0 +1,311 @@
+package plugtest
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/swag"
+)
+
+// errParamsType is returned if the params are neither a struct nor a map
+var errParamsType = errors.New("params must be a struct, a pointer to a struct or a map[string]interface{}")
+
+type operationKey struct{}
+
+// withOperation attaches the operation to the request
+func withOperation(req *http.Request, op *operation) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), operationKey{}, op))
+}
+
+// operationFrom returns the operation the request was created for
+func operationFrom(req *http.Request) (*operation, bool) {
+	op, ok := req.Context().Value(operationKey{}).(*operation)
+	return op, ok
+}
+
+// newRequest encodes the params as the operation defines them
+func newRequest(baseURL string, op *operation, params interface{}) (*http.Request, error) {
+	values, err := paramValues(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		path    = op.PathPattern
+		query   = make(url.Values)
+		header  = make(http.Header)
+		form    = make(url.Values)
+		files   = make(map[string]io.Reader)
+		body    io.Reader
+		bodyCT  string
+		hasForm bool
+	)
+
+	for _, param := range op.params {
+		v, ok := values(param.Name)
+		if !ok {
+			if param.Required && param.In == "path" {
+				return nil, fmt.Errorf("path parameter %q is not set", param.Name)
+			}
+			continue
+		}
+
+		switch param.In {
+		case "path":
+			strs, err := formatParam(param, v)
+			if err != nil {
+				return nil, err
+			}
+			path = strings.Replace(path, "{"+param.Name+"}", url.PathEscape(strings.Join(strs, ",")), -1)
+		case "query":
+			strs, err := formatParam(param, v)
+			if err != nil {
+				return nil, err
+			}
+			query[param.Name] = strs
+		case "header":
+			strs, err := formatParam(param, v)
+			if err != nil {
+				return nil, err
+			}
+			header.Set(param.Name, strings.Join(strs, ","))
+		case "formData":
+			hasForm = true
+			if r, ok := v.Interface().(io.Reader); ok {
+				files[param.Name] = r
+				continue
+			}
+			strs, err := formatParam(param, v)
+			if err != nil {
+				return nil, err
+			}
+			form[param.Name] = strs
+		case "body":
+			body, bodyCT, err = encodeBody(op.consumes, v)
+			if err != nil {
+				return nil, fmt.Errorf("body parameter %q: %w", param.Name, err)
+			}
+		}
+	}
+
+	if hasForm {
+		body, bodyCT, err = encodeForm(form, files)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	u, err := url.Parse(baseURL + path)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(op.Method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if bodyCT != "" {
+		req.Header.Set("Content-Type", bodyCT)
+	}
+	return req, nil
+}
+
+// paramValues returns a lookup of parameter values by the spec parameter name
+func paramValues(params interface{}) (func(name string) (reflect.Value, bool), error) {
+	if params == nil {
+		return func(string) (reflect.Value, bool) { return reflect.Value{}, false }, nil
+	}
+
+	if m, ok := params.(map[string]interface{}); ok {
+		return func(name string) (reflect.Value, bool) {
+			v, ok := m[name]
+			if !ok {
+				return reflect.Value{}, false
+			}
+			return setValue(reflect.ValueOf(v))
+		}, nil
+	}
+
+	pv := reflect.Indirect(reflect.ValueOf(params))
+	if pv.Kind() != reflect.Struct {
+		return nil, errParamsType
+	}
+	return func(name string) (reflect.Value, bool) {
+		// generated params are named after the spec parameters
+		return setValue(pv.FieldByName(swag.ToGoName(name)))
+	}, nil
+}
+
+// setValue dereferences the value and checks if it is set
+func setValue(v reflect.Value) (reflect.Value, bool) {
+	if !v.IsValid() {
+		return v, false
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v, false
+		}
+		// readers are passed as they are
+		if _, ok := v.Interface().(io.Reader); ok {
+			return v, true
+		}
+		return setValue(v.Elem())
+	case reflect.Interface, reflect.Slice, reflect.Map:
+		if v.IsNil() {
+			return v, false
+		}
+	}
+	return v, true
+}
+
+// formatParam formats a simple parameter value
+// according to the parameter collection format
+func formatParam(param spec.Parameter, v reflect.Value) ([]string, error) {
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		s, err := formatValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %q: %w", param.Name, err)
+		}
+		return []string{s}, nil
+	}
+
+	strs := make([]string, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		s, err := formatValue(v.Index(i))
+		if err != nil {
+			return nil, fmt.Errorf("parameter %q: %w", param.Name, err)
+		}
+		strs = append(strs, s)
+	}
+
+	switch param.CollectionFormat {
+	case "multi":
+		return strs, nil
+	case "ssv":
+		return []string{strings.Join(strs, " ")}, nil
+	case "tsv":
+		return []string{strings.Join(strs, "\t")}, nil
+	case "pipes":
+		return []string{strings.Join(strs, "|")}, nil
+	default:
+		return []string{strings.Join(strs, ",")}, nil
+	}
+}
+
+// formatValue formats a single value
+func formatValue(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	switch val := v.Interface().(type) {
+	case encoding.TextMarshaler:
+		b, err := val.MarshalText()
+		return string(b), err
+	case fmt.Stringer:
+		return val.String(), nil
+	case []byte:
+		return string(val), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	default:
+		return "", fmt.Errorf("can't format %s", v.Type())
+	}
+}
+
+// encodeBody encodes the body parameter with the first media type the operation consumes
+func encodeBody(consumes []string, v reflect.Value) (io.Reader, string, error) {
+	mediaType := "application/json"
+	if len(consumes) > 0 {
+		mediaType = consumes[0]
+	}
+
+	switch val := v.Interface().(type) {
+	case io.Reader:
+		return val, mediaType, nil
+	case []byte:
+		return bytes.NewReader(val), mediaType, nil
+	case string:
+		if !isJSON(mediaType) {
+			return strings.NewReader(val), mediaType, nil
+		}
+	}
+
+	if !isJSON(mediaType) {
+		return nil, "", fmt.Errorf("can't encode %s as %s", v.Type(), mediaType)
+	}
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, "", err
+	}
+	return bytes.NewReader(b), mediaType, nil
+}
+
+// encodeForm encodes form parameters as an URL-encoded form,
+// or as a multipart form if there are files
+func encodeForm(form url.Values, files map[string]io.Reader) (io.Reader, string, error) {
+	if len(files) == 0 {
+		return strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", nil
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for name, values := range form {
+		for _, v := range values {
+			if err := mw.WriteField(name, v); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	for name, r := range files {
+		fw, err := mw.CreateFormFile(name, name)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := io.Copy(fw, r); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return &buf, mw.FormDataContentType(), nil
+}
+
+// isJSON checks if the media type is JSON
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
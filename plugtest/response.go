@@ -0,0 +1,120 @@
+package plugtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/validate"
+)
+
+// Response is a response of the API
+type Response struct {
+	*http.Response
+	// Payload is the response body.
+	// The Body can be read again as well
+	Payload []byte
+
+	t testing.TB
+}
+
+func newResponse(t testing.TB, resp *http.Response) *Response {
+	t.Helper()
+
+	payload, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("plugtest: can't read the response: %v", err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(payload))
+
+	return &Response{
+		Response: resp,
+		Payload:  payload,
+		t:        t,
+	}
+}
+
+// ExpectStatus fails the test if the response has another status code
+func (r *Response) ExpectStatus(code int) *Response {
+	r.t.Helper()
+	if r.StatusCode != code {
+		r.t.Errorf("plugtest: expected status %d, got %d: %s", code, r.StatusCode, r.Payload)
+	}
+	return r
+}
+
+// Decode decodes the JSON response payload into v,
+// the test fails if it can't be decoded
+func (r *Response) Decode(v interface{}) {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Payload, v); err != nil {
+		r.t.Fatalf("plugtest: can't decode the response: %v", err)
+	}
+}
+
+// checkResponse checks that the response status is declared for the operation,
+// and the payload matches the declared schema
+func (s *Server) checkResponse(op *operation, r *Response) error {
+	if op.responses == nil {
+		return nil
+	}
+
+	resp, ok := op.responses.StatusCodeResponses[r.StatusCode]
+	if !ok {
+		if op.responses.Default == nil {
+			return fmt.Errorf("status %d is not declared in the spec", r.StatusCode)
+		}
+		resp = *op.responses.Default
+	}
+	if resp.Ref.String() != "" {
+		resolved, err := spec.ResolveResponse(s.spec, resp.Ref)
+		if err != nil {
+			return err
+		}
+		resp = *resolved
+	}
+
+	if resp.Schema == nil {
+		return nil
+	}
+	data, ok, err := decodePayload(r, resp.Schema)
+	if err != nil || !ok {
+		return err
+	}
+
+	res := validate.NewSchemaValidator(resp.Schema, s.spec, "", s.formats).Validate(data)
+	if res.HasErrors() {
+		return fmt.Errorf("status %d response doesn't match the schema: %w", r.StatusCode, res.AsError())
+	}
+	return nil
+}
+
+// decodePayload decodes the payload to validate it.
+// It returns false if the payload can't be validated against a schema.
+func decodePayload(r *Response, schema *spec.Schema) (interface{}, bool, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch {
+	case isJSON(mediaType):
+		if len(r.Payload) == 0 {
+			return nil, false, errors.New("response body is empty")
+		}
+		var data interface{}
+		if err := json.Unmarshal(r.Payload, &data); err != nil {
+			return nil, false, fmt.Errorf("invalid JSON response: %w", err)
+		}
+		return data, true, nil
+	case strings.HasPrefix(mediaType, "text/") && schema.Type.Contains("string"):
+		return string(r.Payload), true, nil
+	default:
+		return nil, false, nil
+	}
+}
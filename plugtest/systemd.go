@@ -0,0 +1,103 @@
+package plugtest
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// NotifySocket is a fake systemd notify socket
+//
+// It sets NOTIFY_SOCKET for the test, so a plug created
+// with plugger.WithSystemd reports its state to it.
+type NotifySocket struct {
+	// Path is the socket path
+	Path string
+
+	t     testing.TB
+	conn  *net.UnixConn
+	state chan string
+}
+
+// NewNotifySocket listens on a fake notify socket until the test completes
+func NewNotifySocket(t testing.TB) *NotifySocket {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "plugtest")
+	if err != nil {
+		t.Fatalf("plugtest: %v", err)
+	}
+	path := filepath.Join(dir, "notify.sock")
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("plugtest: can't listen on the notify socket: %v", err)
+	}
+
+	s := &NotifySocket{
+		Path:  path,
+		t:     t,
+		conn:  conn,
+		state: make(chan string, 64),
+	}
+	go s.read()
+
+	prev, hadPrev := os.LookupEnv("NOTIFY_SOCKET")
+	os.Setenv("NOTIFY_SOCKET", path)
+
+	t.Cleanup(func() {
+		if hadPrev {
+			os.Setenv("NOTIFY_SOCKET", prev)
+		} else {
+			os.Unsetenv("NOTIFY_SOCKET")
+		}
+		conn.Close()
+		os.RemoveAll(dir)
+	})
+	return s
+}
+
+func (s *NotifySocket) read() {
+	defer close(s.state)
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := s.conn.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			if line != "" {
+				s.state <- line
+			}
+		}
+	}
+}
+
+// Wait waits for the state assignment, e.g. "READY=1".
+// The test fails if it isn't received within the timeout.
+// States received before are skipped.
+func (s *NotifySocket) Wait(state string, timeout time.Duration) {
+	s.t.Helper()
+
+	deadline := time.After(timeout)
+	for {
+		select {
+		case got, ok := <-s.state:
+			if !ok {
+				s.t.Fatalf("plugtest: notify socket closed while waiting for %s", state)
+			}
+			if got == state {
+				return
+			}
+		case <-deadline:
+			s.t.Fatalf("plugtest: %s is not received in %v", state, timeout)
+			return
+		}
+	}
+}
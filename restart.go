@@ -0,0 +1,360 @@
+package plugger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// envReadyFD is the descriptor a restarted process reports its readiness to
+const envReadyFD = "PLUGGER_READY_FD"
+
+// handoffPollInterval is how often the accepted connections are checked on restart
+const handoffPollInterval = 10 * time.Millisecond
+
+// WithGracefulRestart restarts the process without dropping connections on the signal
+//
+// On the signal, the plug starts the executable again with the same arguments
+// and environment, and passes it the server and admin listeners
+// the same way the systemd socket activation does.
+// The new process must be served with this option as well.
+// Once its listeners accept connections, the plug shuts down gracefully
+// within the server GracefulTimeout, and Serve returns, so the old process can exit.
+// If the new process fails to start or isn't ready within GracefulTimeout,
+// it is stopped and the plug keeps serving.
+//
+// With WithSystemd the new process becomes the main process of the service,
+// so the service needs NotifyAccess=all to receive its notifications.
+// Listeners can't be passed to another process on Windows.
+func WithGracefulRestart(sig os.Signal) Option {
+	return newOptionServer(func(p *Plug) error {
+		if sig == nil {
+			return errors.New("plugger: no restart signal")
+		}
+		p.restartSignal = sig
+		return nil
+	})
+}
+
+// handleRestart restarts the process on the restart signal until done is closed
+func (p *Plug) handleRestart(done <-chan struct{}) {
+	if p.restartSignal == nil {
+		return
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, p.restartSignal)
+
+	go func() {
+		defer signal.Stop(ch)
+
+		for {
+			select {
+			case sig := <-ch:
+				p.log().Info("restarting", "signal", sig.String())
+				pid, err := p.restart()
+				if err != nil {
+					p.log().Error("restart failed", "error", err)
+					continue
+				}
+				p.log().Info("new process is ready, shutting down", "pid", pid)
+				p.handOver(pid)
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// restart starts the new process with the listeners
+// and waits until it is ready. It returns the new process pid.
+func (p *Plug) restart() (int, error) {
+	var (
+		files []*os.File
+		names []string
+	)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	for _, scheme := range p.boundSchemes() {
+		f, err := listenerFile(p.bound[scheme])
+		if err != nil {
+			return 0, fmt.Errorf("plugger: %s listener can't be passed: %w", scheme, err)
+		}
+		files = append(files, f)
+		names = append(names, scheme)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+
+	ready, readyW, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer ready.Close()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = append(files, readyW)
+	cmd.Env = append(restartEnv(),
+		envListenFDs+"="+strconv.Itoa(len(files)),
+		envListenFDNames+"="+strings.Join(names, ":"),
+		envReadyFD+"="+strconv.Itoa(listenFDsStart+len(files)),
+	)
+
+	err = cmd.Start()
+	readyW.Close()
+	for _, l := range p.bound {
+		setNonblock(l)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// the pipe is closed without a message if the process exits,
+	// and a process that isn't ready in time is stopped
+	timeout := p.gracefulTimeout()
+	ready.SetReadDeadline(time.Now().Add(timeout))
+	if n, err := ready.Read(make([]byte, 1)); n == 0 {
+		cmd.Process.Kill()
+		waitErr := cmd.Wait()
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return 0, fmt.Errorf("plugger: new process isn't ready within %v", timeout)
+		}
+		return 0, fmt.Errorf("plugger: new process has stopped before it was ready: %v", waitErr)
+	}
+	go cmd.Wait()
+
+	return cmd.Process.Pid, nil
+}
+
+// handOver shuts the plug down after the listeners are passed to the new process
+func (p *Plug) handOver(pid int) {
+	// the generated server drops connections that haven't sent a request
+	// before the shutdown, so accepting stops first
+	for _, l := range p.handoff {
+		l.stop()
+	}
+	// waiting for the connections counts toward the graceful timeout
+	ctx, cancel := context.WithTimeout(context.Background(), p.gracefulTimeout())
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+	for !p.handoffQuiesced() && time.Now().Before(deadline) {
+		time.Sleep(handoffPollInterval)
+	}
+
+	// the listeners are served by the new process, so unix sockets
+	// must stay in place and there is nothing to delay
+	for _, l := range p.bound {
+		setUnlinkOnClose(l, false)
+	}
+	if p.systemd {
+		p.stoppingOnce.Do(func() {
+			if err := sdNotify("MAINPID=" + strconv.Itoa(pid)); err != nil {
+				p.log().Warn("systemd notification failed", "error", err)
+			}
+		})
+	}
+
+	// the server drains within the rest of the timeout
+	remaining := time.Until(deadline)
+	if remaining < handoffPollInterval {
+		remaining = handoffPollInterval
+	}
+	setDynParam(p.sv, "GracefulTimeout", remaining)
+	if err := p.shutdown(ctx, false); err != nil {
+		p.log().Error("shutdown failed", "error", err)
+	}
+}
+
+// handoffQuiesced reports whether all accepted connections have been read from
+func (p *Plug) handoffQuiesced() bool {
+	for _, l := range p.handoff {
+		if !l.quiesced() {
+			return false
+		}
+	}
+	return true
+}
+
+// boundSchemes returns schemes of the bound listeners in the order they are passed
+func (p *Plug) boundSchemes() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var schemes []string
+	for _, lf := range listenerFields {
+		if _, ok := p.bound[lf.scheme]; ok {
+			schemes = append(schemes, lf.scheme)
+		}
+	}
+	if _, ok := p.bound[AdminScheme]; ok {
+		schemes = append(schemes, AdminScheme)
+	}
+	return schemes
+}
+
+// notifyRestarted tells the previous process the plug is ready
+func (p *Plug) notifyRestarted(done <-chan struct{}) {
+	fd := os.Getenv(envReadyFD)
+	os.Unsetenv(envReadyFD)
+	if p.restartSignal == nil || fd == "" {
+		return
+	}
+
+	n, err := strconv.Atoi(fd)
+	if err != nil || n < listenFDsStart {
+		p.log().Warn("invalid restart readiness descriptor", envReadyFD, fd)
+		return
+	}
+	f := os.NewFile(uintptr(n), "ready")
+
+	go func() {
+		defer f.Close()
+		select {
+		case <-p.ready:
+			f.Write([]byte{1})
+		case <-done:
+		}
+	}()
+}
+
+// listenerFile returns a duplicate of the listener descriptor
+func listenerFile(l net.Listener) (*os.File, error) {
+	fl, ok := l.(interface {
+		File() (*os.File, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%T has no file descriptor", l)
+	}
+	return fl.File()
+}
+
+// restartEnv returns the environment of the new process
+// without the variables that are bound to this one
+func restartEnv() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		switch kv[:strings.IndexByte(kv+"=", '=')] {
+		case envListenPID, envListenFDs, envListenFDNames, envWatchdogPID, envReadyFD:
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
+
+// handoffListener stops accepting connections once the listener
+// is handed over to the new process, and tracks accepted connections
+// that haven't been read from yet
+type handoffListener struct {
+	net.Listener
+
+	mu        sync.Mutex
+	stopped   bool
+	accepting bool
+	pending   int
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newHandoffListener(l net.Listener) *handoffListener {
+	return &handoffListener{Listener: l, closed: make(chan struct{})}
+}
+
+func (l *handoffListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
+	stopped := l.stopped
+	l.accepting = !stopped
+	l.mu.Unlock()
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if !stopped {
+		conn, err = l.Listener.Accept()
+	}
+
+	l.mu.Lock()
+	l.accepting = false
+	stopped = l.stopped
+	if conn != nil {
+		l.pending++
+	}
+	l.mu.Unlock()
+
+	if conn != nil {
+		return &handoffConn{Conn: conn, l: l}, nil
+	}
+	if stopped {
+		// the new process accepts connections until the server is shut down
+		<-l.closed
+		return nil, errHandedOver
+	}
+	return nil, err
+}
+
+func (l *handoffListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return l.Listener.Close()
+}
+
+// stop interrupts a pending Accept and stops accepting
+func (l *handoffListener) stop() {
+	l.mu.Lock()
+	l.stopped = true
+	l.mu.Unlock()
+
+	if dl, ok := l.Listener.(interface{ SetDeadline(time.Time) error }); ok {
+		dl.SetDeadline(time.Now())
+	}
+}
+
+// quiesced reports whether all accepted connections have been read from
+func (l *handoffListener) quiesced() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !l.accepting && l.pending == 0
+}
+
+func (l *handoffListener) read() {
+	l.mu.Lock()
+	l.pending--
+	l.mu.Unlock()
+}
+
+var errHandedOver = errors.New("plugger: listener is handed over to the new process")
+
+// handoffConn reports the first read to the listener
+type handoffConn struct {
+	net.Conn
+	l    *handoffListener
+	once sync.Once
+}
+
+func (c *handoffConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.once.Do(c.l.read)
+	return n, err
+}
+
+func (c *handoffConn) Close() error {
+	c.once.Do(c.l.read)
+	return c.Conn.Close()
+}
@@ -0,0 +1,12 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package plugger
+
+import "net"
+
+// setNonblock does nothing, listeners can't be passed to a process
+func setNonblock(net.Listener) {}
+
+// setUnlinkOnClose does nothing, listeners can't be passed to a process
+func setUnlinkOnClose(net.Listener, bool) {}
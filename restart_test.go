@@ -0,0 +1,204 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package plugger_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/runtime/middleware"
+
+	"github.com/ilyakaznacheev/go-plugger"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi/operations"
+)
+
+// newRestartPlug creates a plug that answers with the process pid
+func newRestartPlug(t *testing.T, opts ...plugger.Option) *plugger.Plug {
+	t.Helper()
+
+	p, err := plugger.New(restapi.NewServer(nil), newGreetingAPI(t), append([]plugger.Option{
+		plugger.WithHost("127.0.0.1"), plugger.WithPort(0),
+		plugger.WithGracefulTimeout(10 * time.Second),
+		plugger.WithGracefulRestart(syscall.SIGUSR2),
+		plugger.WithMetrics("/metrics"),
+		plugger.WithHealthChecks(),
+		plugger.WithHandler("getGreeting", func(operations.GetGreetingParams) middleware.Responder {
+			return operations.NewGetGreetingOK().WithPayload(strconv.Itoa(os.Getpid()))
+		}),
+	}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// getPid calls the plug and returns the pid of the process that served it
+func getPid(t *testing.T, addr string) int {
+	t.Helper()
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/hello", addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(body)))
+	if resp.StatusCode != http.StatusOK || err != nil {
+		t.Fatalf("GET /hello = %d %q", resp.StatusCode, body)
+	}
+	return pid
+}
+
+// restartAs makes the restarted process run the test only
+func restartAs(t *testing.T, test string) {
+	args := os.Args
+	os.Args = []string{args[0], "-test.run=^" + test + "$"}
+	t.Cleanup(func() { os.Args = args })
+}
+
+// stopProcess terminates the restarted process and waits until it stops
+func stopProcess(t *testing.T, pid int) {
+	t.Helper()
+
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the plug waits for the process it has started
+	proc.Signal(syscall.SIGTERM)
+	for deadline := time.Now().Add(10 * time.Second); proc.Signal(syscall.Signal(0)) == nil; {
+		if time.Now().After(deadline) {
+			proc.Kill()
+			t.Fatal("restarted process hasn't stopped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWithGracefulRestart(t *testing.T) {
+	if os.Getenv("PLUGGER_READY_FD") != "" {
+		// the restarted process serves until it is terminated
+		if err := newRestartPlug(t).Serve(); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	restartAs(t, "TestWithGracefulRestart")
+
+	p := newRestartPlug(t, plugger.WithoutSignalHandling())
+	errc := make(chan error, 1)
+	go func() { errc <- p.Serve() }()
+	<-p.Ready()
+
+	addr := p.Addrs()["http"].String()
+	if pid := getPid(t, addr); pid != os.Getpid() {
+		t.Fatalf("served by %d before the restart, want %d", pid, os.Getpid())
+	}
+
+	// a pending Accept must be interrupted with the metrics listener wrapped around,
+	// otherwise the plug waits for the whole graceful timeout
+	start := time.Now()
+	syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("Serve() = %v", err)
+		}
+		if d := time.Since(start); d > 5*time.Second {
+			t.Errorf("handover took %v", d)
+		}
+	case <-time.After(20 * time.Second):
+		t.Fatal("Serve hasn't returned after the restart")
+	}
+
+	pid := getPid(t, addr)
+	if pid == os.Getpid() {
+		t.Fatal("served by the old process after the restart")
+	}
+	stopProcess(t, pid)
+}
+
+func TestWithGracefulRestartDeadline(t *testing.T) {
+	if os.Getenv("PLUGGER_READY_FD") != "" {
+		if err := newRestartPlug(t).Serve(); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	restartAs(t, "TestWithGracefulRestartDeadline")
+
+	const timeout = time.Second
+	p := newRestartPlug(t, plugger.WithoutSignalHandling(), plugger.WithGracefulTimeout(timeout))
+	errc := make(chan error, 1)
+	go func() { errc <- p.Serve() }()
+	<-p.Ready()
+	addr := p.Addrs()["http"].String()
+
+	// a connection without a request keeps the handover waiting
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	getPid(t, addr)
+
+	start := time.Now()
+	syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	select {
+	case <-errc:
+	case <-time.After(20 * time.Second):
+		t.Fatal("Serve hasn't returned after the restart")
+	}
+	// waiting for the connection and draining share one deadline
+	if d := time.Since(start); d > timeout+timeout/2 {
+		t.Errorf("handover took %v, want about %v", d, timeout)
+	}
+
+	// readiness fails once the plug is handed over
+	rec := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /readyz = %d after the handover, want 503", rec.Code)
+	}
+
+	stopProcess(t, getPid(t, addr))
+}
+
+func TestWithGracefulRestartNotReady(t *testing.T) {
+	if os.Getenv("PLUGGER_READY_FD") != "" {
+		// the restarted process never gets ready
+		select {}
+	}
+	restartAs(t, "TestWithGracefulRestartNotReady")
+
+	logger := &recordLogger{}
+	p := newRestartPlug(t, plugger.WithoutSignalHandling(),
+		plugger.WithGracefulTimeout(200*time.Millisecond),
+		plugger.WithStructuredLogger(logger))
+	servePlug(t, p)
+	addr := p.Addrs()["http"].String()
+
+	syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	for deadline := time.Now().Add(10 * time.Second); !strings.Contains(logger.String(), "ERROR restart failed"); {
+		if time.Now().After(deadline) {
+			t.Fatalf("restart hasn't failed:\n%s", logger)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the plug keeps serving
+	if pid := getPid(t, addr); pid != os.Getpid() {
+		t.Errorf("served by %d after the failed restart, want %d", pid, os.Getpid())
+	}
+}
@@ -0,0 +1,33 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package plugger
+
+import (
+	"net"
+	"syscall"
+)
+
+// setNonblock puts the listener descriptor back to the non-blocking mode.
+// Descriptors passed to a process are switched to the blocking mode,
+// and the mode is shared with the listener.
+func setNonblock(l net.Listener) {
+	sc, ok := l.(syscall.Conn)
+	if !ok {
+		return
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return
+	}
+	rc.Control(func(fd uintptr) {
+		syscall.SetNonblock(int(fd), true)
+	})
+}
+
+// setUnlinkOnClose sets whether closing the unix listener removes the socket file
+func setUnlinkOnClose(l net.Listener, unlink bool) {
+	if ul, ok := l.(*net.UnixListener); ok {
+		ul.SetUnlinkOnClose(unlink)
+	}
+}
@@ -0,0 +1,143 @@
+package plugger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"time"
+)
+
+// WithoutSignalHandling stops the generated server from handling signals
+//
+// The generated server shuts down on SIGINT and SIGTERM by itself.
+// With this option the server is only shut down by Plug.Shutdown
+// or by cancelling the context passed to ServeContext,
+// so the application can orchestrate the shutdown,
+// or serve several plugs in one process.
+func WithoutSignalHandling() Option {
+	return newOptionPlug(func(p *Plug) error {
+		p.ownSignals = true
+		return nil
+	})
+}
+
+// WithSignals replaces the generated server signal handling
+//
+// The plug is shut down with Plug.Shutdown once any of the signals is received,
+// so the shutdown delay applies. The generated server doesn't handle signals
+// anymore, see WithoutSignalHandling.
+func WithSignals(sigs ...os.Signal) Option {
+	return newOptionPlug(func(p *Plug) error {
+		if len(sigs) == 0 {
+			return errors.New("plugger: no signals to handle")
+		}
+		p.ownSignals = true
+		p.signals = append(p.signals, sigs...)
+		return nil
+	})
+}
+
+// WithShutdownDelay delays the server shutdown
+//
+// When the plug is shut down with Plug.Shutdown, the readiness check
+// fails right away, but the listeners keep serving for the delay,
+// so load balancers have time to stop sending new requests.
+// It doesn't apply to the generated server signal handling.
+func WithShutdownDelay(d time.Duration) Option {
+	return newOptionPlug(func(p *Plug) error {
+		if d < 0 {
+			return fmt.Errorf("plugger: shutdown delay %v is negative", d)
+		}
+		p.shutdownDelay = d
+		return nil
+	})
+}
+
+// stopServerSignals undoes the generated server signal handling
+//
+// The generated interrupt handler ignores signals once the server
+// is interrupted, so the flag is set before Serve. The generated Serve
+// subscribes the interrupt channel to signals before it starts the servers,
+// so the channel is closed once the listeners accept connections,
+// and the generated handler returns.
+func (p *Plug) stopServerSignals(done <-chan struct{}) {
+	if !p.ownSignals {
+		return
+	}
+
+	if interrupted := dynField(p.sv, "interrupted"); interrupted.IsValid() && interrupted.Kind() == reflect.Bool {
+		interrupted.SetBool(true)
+	}
+
+	field := dynField(p.sv, "interrupt")
+	if !field.IsValid() {
+		return
+	}
+	ch, ok := field.Interface().(chan os.Signal)
+	if !ok || ch == nil {
+		return
+	}
+
+	// the plug is ready once the server listeners accept connections,
+	// without them the readiness doesn't tell that Serve has subscribed
+	ready := p.ready
+	servers := len(p.bound)
+	if _, ok := p.bound[AdminScheme]; ok {
+		servers--
+	}
+	if servers == 0 {
+		ready = nil
+	}
+
+	go func() {
+		select {
+		case <-ready:
+		case <-done:
+		}
+		// no signals are sent to the channel once Stop returns
+		signal.Stop(ch)
+		close(ch)
+	}()
+}
+
+// handleSignals shuts the plug down on the signals until done is closed
+func (p *Plug) handleSignals(done <-chan struct{}) {
+	if len(p.signals) == 0 {
+		return
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, p.signals...)
+
+	go func() {
+		defer signal.Stop(ch)
+
+		select {
+		case sig := <-ch:
+			p.log().Info("shutting down", "signal", sig.String())
+			if err := p.Shutdown(context.Background()); err != nil {
+				p.log().Error("shutdown failed", "error", err)
+			}
+		case <-done:
+		}
+	}()
+}
+
+// delayShutdown waits for the shutdown delay, until ctx is done
+// or the server has stopped, so a stopped plug isn't delayed again
+func (p *Plug) delayShutdown(ctx context.Context, done <-chan struct{}) {
+	if p.shutdownDelay <= 0 {
+		return
+	}
+
+	t := time.NewTimer(p.shutdownDelay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	case <-done:
+	}
+}
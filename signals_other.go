@@ -0,0 +1,11 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package plugger
+
+import "os"
+
+// signalNames are the signals that can be configured by name
+var signalNames = map[string]os.Signal{
+	"SIGINT": os.Interrupt,
+}
@@ -0,0 +1,112 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package plugger_test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ilyakaznacheev/go-plugger"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi"
+)
+
+// syncBuffer is a log output safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWithoutSignalHandling(t *testing.T) {
+	// the application handles the signal instead of the generated server
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT)
+	defer signal.Stop(sigs)
+
+	var logs syncBuffer
+	api := newGreetingAPI(t)
+	configureAPI(api)
+	p, err := plugger.New(restapi.NewServer(nil), api,
+		plugger.WithHost("127.0.0.1"), plugger.WithPort(0),
+		plugger.WithLogger(log.New(&logs, "", 0).Printf),
+		plugger.WithGracefulTimeout(time.Second), plugger.WithoutSignalHandling())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- p.Serve() }()
+
+	// the signal may come before the generated server is serving
+	syscall.Kill(os.Getpid(), syscall.SIGINT)
+	<-sigs
+	<-p.Ready()
+	syscall.Kill(os.Getpid(), syscall.SIGINT)
+	<-sigs
+
+	select {
+	case err := <-errc:
+		t.Fatalf("the server has stopped on the signal: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	conn, err := net.Dial("tcp", p.Addrs()["http"].String())
+	if err != nil {
+		t.Fatalf("the server doesn't accept connections: %v", err)
+	}
+	conn.Close()
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(logs.String(), "Shutting down") {
+		t.Errorf("the generated server has handled the signal:\n%s", logs.String())
+	}
+}
+
+func TestWithSignals(t *testing.T) {
+	p, err := plugger.New(restapi.NewServer(nil), newGreetingAPI(t),
+		plugger.WithHost("127.0.0.1"), plugger.WithPort(0),
+		plugger.WithSignals(syscall.SIGUSR1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- p.Serve() }()
+	<-p.Ready()
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server hasn't stopped on the signal")
+	}
+}
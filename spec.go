@@ -0,0 +1,113 @@
+package plugger
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/go-openapi/loads"
+	"github.com/go-openapi/spec"
+)
+
+// specOperation is an operation defined in the spec
+type specOperation struct {
+	Method string
+	Path   string
+	*spec.Operation
+}
+
+// specOf returns the spec document of a generated API
+func specOf(apiv reflect.Value) (*loads.Document, error) {
+	field := dynField(apiv, "spec")
+	if !field.IsValid() {
+		return nil, ErrNoSpec
+	}
+	doc, ok := field.Interface().(*loads.Document)
+	if !ok || doc == nil {
+		return nil, ErrNoSpec
+	}
+	return doc, nil
+}
+
+// specOperations returns all operations defined in the API spec
+// sorted by path and method
+func specOperations(apiv reflect.Value) ([]specOperation, error) {
+	doc, err := specOf(apiv)
+	if err != nil {
+		return nil, err
+	}
+
+	var ops []specOperation
+	if sw := doc.Spec(); sw != nil && sw.Paths != nil {
+		for path, item := range sw.Paths.Paths {
+			for method, op := range pathOperations(item) {
+				ops = append(ops, specOperation{Method: method, Path: path, Operation: op})
+			}
+		}
+	}
+
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Path != ops[j].Path {
+			return ops[i].Path < ops[j].Path
+		}
+		return ops[i].Method < ops[j].Method
+	})
+	return ops, nil
+}
+
+// pathOperations returns operations of the path by method
+func pathOperations(item spec.PathItem) map[string]*spec.Operation {
+	ops := make(map[string]*spec.Operation)
+	for method, op := range map[string]*spec.Operation{
+		http.MethodGet:     item.Get,
+		http.MethodPut:     item.Put,
+		http.MethodPost:    item.Post,
+		http.MethodDelete:  item.Delete,
+		http.MethodOptions: item.Options,
+		http.MethodHead:    item.Head,
+		http.MethodPatch:   item.Patch,
+	} {
+		if op != nil {
+			ops[method] = op
+		}
+	}
+	return ops
+}
+
+// operationByID finds an operation by its operationId
+func operationByID(apiv reflect.Value, operationID string) (specOperation, bool, error) {
+	ops, err := specOperations(apiv)
+	if err != nil {
+		return specOperation{}, false, err
+	}
+	for _, op := range ops {
+		if op.ID == operationID {
+			return op, true, nil
+		}
+	}
+	return specOperation{}, false, nil
+}
+
+// operationsByTag finds all operations with the tag
+func operationsByTag(apiv reflect.Value, tag string) ([]specOperation, error) {
+	ops, err := specOperations(apiv)
+	if err != nil {
+		return nil, err
+	}
+
+	var tagged []specOperation
+	for _, op := range ops {
+		for _, t := range op.Tags {
+			if t == tag {
+				tagged = append(tagged, op)
+				break
+			}
+		}
+	}
+	return tagged, nil
+}
+
+// Spec returns the spec document of the API
+func (p *Plug) Spec() (*loads.Document, error) {
+	return specOf(p.apiv)
+}
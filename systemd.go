@@ -0,0 +1,238 @@
+package plugger
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/swag"
+)
+
+// systemd environment variables
+const (
+	envListenPID     = "LISTEN_PID"
+	envListenFDs     = "LISTEN_FDS"
+	envListenFDNames = "LISTEN_FDNAMES"
+	envNotifySocket  = "NOTIFY_SOCKET"
+	envWatchdogUSec  = "WATCHDOG_USEC"
+	envWatchdogPID   = "WATCHDOG_PID"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd
+const listenFDsStart = 3
+
+// WithSystemd integrates the plug with systemd
+//
+// Listeners passed by the socket activation with LISTEN_FDS are served
+// instead of opening new ones. A listener named after a scheme with
+// FileDescriptorName=http, https or unix serves that scheme, and the one named
+// admin is served as the admin listener, see WithAdminListener. Unnamed listeners
+// serve the enabled schemes, see WithEnabledListeners, in the passed order.
+// The plug isn't served if a named listener has its scheme disabled.
+//
+// The service manager is notified over NOTIFY_SOCKET with READY=1
+// once the listeners accept connections and with STOPPING=1
+// once the shutdown starts. If the watchdog is enabled with WATCHDOG_USEC,
+// the plug pings it twice per the interval while serving.
+//
+// Without the systemd environment variables the plug works as usual.
+func WithSystemd() Option {
+	return newOptionServer(func(p *Plug) error {
+		p.systemd = true
+		return nil
+	})
+}
+
+// activateSockets adds listeners passed by systemd to the injected ones
+func (p *Plug) activateSockets() error {
+	// restarted processes get listeners the same way
+	if !p.systemd && p.restartSignal == nil {
+		return nil
+	}
+
+	files, names, err := listenFiles()
+	if err != nil || len(files) == 0 {
+		return err
+	}
+
+	listeners := make([]net.Listener, len(files))
+	for i, f := range files {
+		// the listener uses a duplicate of the descriptor
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("plugger: systemd listener %d: %w", i+listenFDsStart, err)
+		}
+		// a restarted process owns the unix sockets, unlike systemd
+		if os.Getenv(envReadyFD) != "" {
+			setUnlinkOnClose(l, true)
+		}
+		listeners[i] = l
+	}
+
+	if p.listeners == nil {
+		p.listeners = make(map[string]net.Listener)
+	}
+
+	enabled, _ := getDynParam(p.sv, "EnabledListeners").([]string)
+
+	// named listeners first, the rest fill the enabled schemes in order
+	var unnamed []net.Listener
+	for i, l := range listeners {
+		if names[i] == AdminScheme && p.admin != nil {
+			p.admin.inherited = l
+			continue
+		}
+		if _, ok := listenerField(names[i]); ok {
+			// the server would never serve it
+			if len(enabled) > 0 && !swag.ContainsStrings(enabled, names[i]) {
+				for _, l := range listeners {
+					l.Close()
+				}
+				return fmt.Errorf("%w: systemd listener %d is named %s", ErrSchemeNotEnabled, i+listenFDsStart, names[i])
+			}
+			p.listeners[names[i]] = l
+			continue
+		}
+		unnamed = append(unnamed, l)
+	}
+
+	if len(enabled) == 0 {
+		enabled = []string{schemeHTTP, schemeHTTPS, schemeUnix}
+	}
+	for _, scheme := range enabled {
+		if len(unnamed) == 0 {
+			break
+		}
+		if _, ok := p.listeners[scheme]; ok {
+			continue
+		}
+		p.listeners[scheme] = unnamed[0]
+		unnamed = unnamed[1:]
+	}
+
+	if len(unnamed) > 0 {
+		for _, l := range unnamed {
+			l.Close()
+		}
+		return fmt.Errorf("plugger: %d systemd listeners don't match any enabled scheme", len(unnamed))
+	}
+	return nil
+}
+
+// listenFiles returns the files passed by the socket activation with their names.
+// The environment is cleared, so child processes don't use the files.
+func listenFiles() ([]*os.File, []string, error) {
+	defer func() {
+		os.Unsetenv(envListenPID)
+		os.Unsetenv(envListenFDs)
+		os.Unsetenv(envListenFDNames)
+	}()
+
+	if pid := os.Getenv(envListenPID); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		// passed to another process
+		return nil, nil, nil
+	}
+	nfds := os.Getenv(envListenFDs)
+	if nfds == "" {
+		return nil, nil, nil
+	}
+	n, err := strconv.Atoi(nfds)
+	if err != nil || n < 0 {
+		return nil, nil, fmt.Errorf("plugger: invalid %s=%q", envListenFDs, nfds)
+	}
+
+	names := strings.Split(os.Getenv(envListenFDNames), ":")
+	files := make([]*os.File, n)
+	fileNames := make([]string, n)
+	for i := range files {
+		if i < len(names) {
+			fileNames[i] = names[i]
+		}
+		files[i] = os.NewFile(uintptr(listenFDsStart+i), fileNames[i])
+	}
+	return files, fileNames, nil
+}
+
+// notifySystemd reports the readiness and runs the watchdog until done is closed
+func (p *Plug) notifySystemd(done <-chan struct{}) {
+	if !p.systemd || os.Getenv(envNotifySocket) == "" {
+		return
+	}
+
+	go func() {
+		select {
+		case <-p.ready:
+		case <-done:
+			return
+		}
+		if err := sdNotify("READY=1\nMAINPID=" + strconv.Itoa(os.Getpid())); err != nil {
+			p.log().Warn("systemd notification failed", "error", err)
+		}
+
+		interval, ok := watchdogInterval()
+		if !ok {
+			return
+		}
+		t := time.NewTicker(interval / 2)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				sdNotify("WATCHDOG=1")
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// notifyStopping tells systemd the shutdown has started
+func (p *Plug) notifyStopping() {
+	if !p.systemd {
+		return
+	}
+	p.stoppingOnce.Do(func() {
+		if err := sdNotify("STOPPING=1"); err != nil {
+			p.log().Warn("systemd notification failed", "error", err)
+		}
+	})
+}
+
+// watchdogInterval returns the watchdog interval if it is enabled for the process
+func watchdogInterval() (time.Duration, bool) {
+	if pid := os.Getenv(envWatchdogPID); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, false
+	}
+	usec, err := strconv.ParseInt(os.Getenv(envWatchdogUSec), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, false
+	}
+	return time.Duration(usec) * time.Microsecond, true
+}
+
+// sdNotify sends the state to the systemd notify socket
+func sdNotify(state string) error {
+	socket := os.Getenv(envNotifySocket)
+	if socket == "" {
+		return nil
+	}
+
+	addr := &net.UnixAddr{Name: socket, Net: "unixgram"}
+	// abstract socket namespace
+	if strings.HasPrefix(socket, "@") {
+		addr.Name = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
@@ -0,0 +1,151 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package plugger_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ilyakaznacheev/go-plugger"
+	"github.com/ilyakaznacheev/go-plugger/example/simple_server/restapi"
+	"github.com/ilyakaznacheev/go-plugger/plugtest"
+)
+
+func TestWithSystemd(t *testing.T) {
+	ns := plugtest.NewNotifySocket(t)
+	os.Setenv("WATCHDOG_USEC", "100000")
+	defer os.Unsetenv("WATCHDOG_USEC")
+
+	p, err := plugger.New(restapi.NewServer(nil), newGreetingAPI(t),
+		plugger.WithSystemd(), plugger.WithHost("127.0.0.1"), plugger.WithPort(0),
+		plugger.WithoutSignalHandling())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- p.Serve() }()
+	ns.Wait("READY=1", 5*time.Second)
+	ns.Wait("WATCHDOG=1", 5*time.Second)
+
+	go p.Shutdown(context.Background())
+	ns.Wait("STOPPING=1", 5*time.Second)
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// envActivationAddr tells the test binary to run as a socket activated child
+const envActivationAddr = "PLUGGER_TEST_ACTIVATION_ADDR"
+
+func TestWithSystemdSocketActivation(t *testing.T) {
+	if addr := os.Getenv(envActivationAddr); addr != "" {
+		serveActivated(t, addr)
+		return
+	}
+	if out, err := runActivated(t, "TestWithSystemdSocketActivation", "http"); err != nil {
+		t.Fatalf("socket activated plug failed: %v\n%s", err, out)
+	}
+}
+
+func TestWithSystemdSocketActivationNotEnabled(t *testing.T) {
+	if os.Getenv(envActivationAddr) != "" {
+		os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+		p, err := plugger.New(restapi.NewServer(nil), newGreetingAPI(t),
+			plugger.WithSystemd(), plugger.WithEnabledListeners([]string{"http"}),
+			plugger.WithHost("127.0.0.1"), plugger.WithPort(0),
+			plugger.WithoutSignalHandling())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the https listener would never get ready
+		errc := make(chan error, 1)
+		go func() { errc <- p.Serve() }()
+		select {
+		case err := <-errc:
+			if !errors.Is(err, plugger.ErrSchemeNotEnabled) {
+				t.Errorf("Serve() = %v, want %v", err, plugger.ErrSchemeNotEnabled)
+			}
+		case <-p.Ready():
+			p.Shutdown(context.Background())
+			t.Error("plug is ready")
+		case <-time.After(5 * time.Second):
+			t.Error("Serve hasn't returned")
+		}
+		return
+	}
+	if out, err := runActivated(t, "TestWithSystemdSocketActivationNotEnabled", "https"); err != nil {
+		t.Fatalf("socket activated plug failed: %v\n%s", err, out)
+	}
+}
+
+// runActivated runs the test in a child process with a socket
+// passed as systemd does, named name
+func runActivated(t *testing.T, test, name string) ([]byte, error) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	f, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// systemd passes the sockets starting from fd 3
+	cmd := exec.Command(os.Args[0], "-test.run=^"+test+"$")
+	cmd.Env = append(os.Environ(),
+		envActivationAddr+"="+l.Addr().String(),
+		"LISTEN_FDS=1", "LISTEN_FDNAMES="+name)
+	cmd.ExtraFiles = []*os.File{f}
+	return cmd.CombinedOutput()
+}
+
+// serveActivated serves the plug on the inherited socket and calls it
+func serveActivated(t *testing.T, addr string) {
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+
+	api := newGreetingAPI(t)
+	configureAPI(api)
+	p, err := plugger.New(restapi.NewServer(nil), api, plugger.WithSystemd(), plugger.WithoutSignalHandling())
+	if err != nil {
+		t.Fatal(err)
+	}
+	go p.Serve()
+	defer p.Shutdown(context.Background())
+	<-p.Ready()
+
+	if got := p.Addrs()["http"].String(); got != addr {
+		t.Errorf("http address = %s, want the inherited %s", got, addr)
+	}
+	for _, env := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		if _, ok := os.LookupEnv(env); ok {
+			t.Errorf("%s is not unset", env)
+		}
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/hello", addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(string(body), "Hello") {
+		t.Errorf("GET /hello = %d %q", resp.StatusCode, body)
+	}
+}
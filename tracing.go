@@ -0,0 +1,297 @@
+package plugger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	chimw "github.com/go-chi/chi/middleware"
+)
+
+// W3C trace context headers
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+// ErrInvalidTraceParent is returned if a traceparent header can't be parsed
+var ErrInvalidTraceParent = errors.New("plugger: invalid traceparent")
+
+// SpanContext identifies a span as defined by the W3C trace context
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	// Sampled is the sampled trace flag
+	Sampled bool
+	// TraceState is the vendor-specific tracestate header value
+	TraceState string
+}
+
+// IsValid checks if the trace and span IDs are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// TraceParent formats the span context as a traceparent header value
+func (sc SpanContext) TraceParent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s",
+		hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:]), flags)
+}
+
+// ParseTraceParent parses a traceparent header value
+func ParseTraceParent(s string) (SpanContext, error) {
+	var sc SpanContext
+
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, ErrInvalidTraceParent
+	}
+	// version 00 has exactly 4 fields, future versions may have more
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, ErrInvalidTraceParent
+	}
+
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil || len(parts[1]) != 32 {
+		return sc, ErrInvalidTraceParent
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil || len(parts[2]) != 16 {
+		return sc, ErrInvalidTraceParent
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil || len(parts[3]) != 2 {
+		return sc, ErrInvalidTraceParent
+	}
+	if !sc.IsValid() {
+		return sc, ErrInvalidTraceParent
+	}
+
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+// Span is a traced request
+type Span struct {
+	// Name is the operationId, or "HTTP <method>"
+	// if the request doesn't match any operation
+	Name        string
+	SpanContext SpanContext
+	// Parent is the remote parent span context from the request headers,
+	// it is invalid if the trace was started by the plug
+	Parent     SpanContext
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]interface{}
+	// StatusCode is the HTTP response status
+	StatusCode int
+	// Err is the error passed to the API ServeError, if any
+	Err error
+}
+
+// SpanExporter exports finished spans
+//
+// ExportSpan is called once a request is served,
+// so it must not block for long.
+type SpanExporter interface {
+	ExportSpan(span *Span)
+}
+
+// InMemoryExporter keeps finished spans in memory, it is useful for tests
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+// NewInMemoryExporter creates a new in-memory span exporter
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpan stores the span
+func (e *InMemoryExporter) ExportSpan(span *Span) {
+	e.mu.Lock()
+	e.spans = append(e.spans, span)
+	e.mu.Unlock()
+}
+
+// Spans returns all exported spans
+func (e *InMemoryExporter) Spans() []*Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*Span(nil), e.spans...)
+}
+
+// Reset drops all exported spans
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	e.spans = nil
+	e.mu.Unlock()
+}
+
+type spanKey struct{}
+
+// SpanContextFrom returns the context of the span traced for the request
+func SpanContextFrom(ctx context.Context) (SpanContext, bool) {
+	if span, ok := ctx.Value(spanKey{}).(*Span); ok {
+		return span.SpanContext, true
+	}
+	return SpanContext{}, false
+}
+
+// InjectTraceContext sets trace context headers of the request span to h,
+// so the trace can be continued by outgoing requests
+func InjectTraceContext(ctx context.Context, h http.Header) {
+	sc, ok := SpanContextFrom(ctx)
+	if !ok {
+		return
+	}
+	h.Set(TraceParentHeader, sc.TraceParent())
+	if sc.TraceState != "" {
+		h.Set(TraceStateHeader, sc.TraceState)
+	}
+}
+
+// WithTracing traces every request served by the plug
+//
+// A span is started for each request and named after the operationId.
+// The trace is continued from the traceparent and tracestate request headers,
+// and the span trace context is returned in the response headers.
+// The response status and the error passed to the API ServeError
+// are recorded to the span. Finished spans are passed to the exporter.
+func WithTracing(exp SpanExporter) Option {
+	return newOptionRouter(func(p *Plug) error {
+		if exp == nil {
+			return errors.New("plugger: span exporter is nil")
+		}
+		p.r.Use(tracingMiddleware(exp))
+		p.tracing = true
+		return nil
+	})
+}
+
+func tracingMiddleware(exp SpanExporter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := startSpan(r)
+			ctx := context.WithValue(r.Context(), spanKey{}, span)
+			ctx, errs := withErrorRecorder(ctx)
+			InjectTraceContext(ctx, w.Header())
+
+			ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			span.End = time.Now()
+			span.StatusCode = ww.Status()
+			if span.StatusCode == 0 {
+				span.StatusCode = http.StatusOK
+			}
+			span.Attributes["http.status_code"] = span.StatusCode
+			span.Err = errs.err()
+
+			exp.ExportSpan(span)
+		})
+	}
+}
+
+// startSpan creates a span for the request
+func startSpan(r *http.Request) *Span {
+	span := &Span{
+		Name:  "HTTP " + r.Method,
+		Start: time.Now(),
+		Attributes: map[string]interface{}{
+			"http.method":   r.Method,
+			"http.target":   r.URL.RequestURI(),
+			"net.peer.addr": remoteHost(r),
+		},
+	}
+
+	if op, ok := OperationFrom(r.Context()); ok {
+		span.Name = op.ID
+		span.Attributes["operation.id"] = op.ID
+		span.Attributes["http.route"] = op.PathPattern
+	}
+
+	parent, err := ParseTraceParent(r.Header.Get(TraceParentHeader))
+	if err == nil {
+		span.Parent = parent
+		span.SpanContext.TraceID = parent.TraceID
+		span.SpanContext.Sampled = parent.Sampled
+		span.SpanContext.TraceState = r.Header.Get(TraceStateHeader)
+	} else {
+		rand.Read(span.SpanContext.TraceID[:])
+		span.SpanContext.Sampled = true
+	}
+	rand.Read(span.SpanContext.SpanID[:])
+
+	return span
+}
+
+type errorRecorderKey struct{}
+
+// errorRecorder keeps the error passed to the API ServeError for a request
+type errorRecorder struct {
+	mu  sync.Mutex
+	e   error
+	set bool
+}
+
+// withErrorRecorder adds an error recorder to the context,
+// or returns the one the context already has
+func withErrorRecorder(ctx context.Context) (context.Context, *errorRecorder) {
+	if rec, ok := ctx.Value(errorRecorderKey{}).(*errorRecorder); ok {
+		return ctx, rec
+	}
+	rec := &errorRecorder{}
+	return context.WithValue(ctx, errorRecorderKey{}, rec), rec
+}
+
+func (rec *errorRecorder) record(err error) {
+	rec.mu.Lock()
+	if !rec.set {
+		rec.e, rec.set = err, true
+	}
+	rec.mu.Unlock()
+}
+
+func (rec *errorRecorder) err() error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.e
+}
+
+// recordServeErrors wraps the API ServeError to record errors
+// of the requests that have an error recorder in their context.
+// ServeError that is already wrapped is kept as is,
+// so the handler can be built any number of times.
+func recordServeErrors(apiv reflect.Value) {
+	serveError, ok := getDynParam(apiv, "ServeError").(func(http.ResponseWriter, *http.Request, error))
+	if !ok || serveError == nil || isErrorRecording(serveError) {
+		return
+	}
+	setDynParam(apiv, "ServeError", errorRecording(serveError))
+}
+
+func errorRecording(serveError func(http.ResponseWriter, *http.Request, error)) func(http.ResponseWriter, *http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		if rec, ok := r.Context().Value(errorRecorderKey{}).(*errorRecorder); ok {
+			rec.record(err)
+		}
+		serveError(w, r, err)
+	}
+}
+
+// isErrorRecording checks if f is returned by errorRecording.
+// All closures of a function literal share the code pointer.
+func isErrorRecording(f func(http.ResponseWriter, *http.Request, error)) bool {
+	return reflect.ValueOf(f).Pointer() == reflect.ValueOf(errorRecording(nil)).Pointer()
+}
@@ -0,0 +1,167 @@
+package plugger_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ilyakaznacheev/go-plugger"
+)
+
+// tracedPlug creates a plug that exports spans to the returned exporter
+func tracedPlug(t *testing.T) (*plugger.Plug, *plugger.InMemoryExporter) {
+	t.Helper()
+
+	api := newGreetingAPI(t)
+	configureAPI(api)
+	exp := plugger.NewInMemoryExporter()
+	p, err := plugger.NewAPIPlug(api, plugger.WithTracing(exp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, exp
+}
+
+// exportedSpan returns the only span exported
+func exportedSpan(t *testing.T, exp *plugger.InMemoryExporter) *plugger.Span {
+	t.Helper()
+
+	spans := exp.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("exported %d spans, want 1", len(spans))
+	}
+	return spans[0]
+}
+
+func TestWithTracing(t *testing.T) {
+	if _, err := plugger.NewAPIPlug(newGreetingAPI(t), plugger.WithTracing(nil)); err == nil {
+		t.Error("no error for a nil exporter")
+	}
+
+	p, exp := tracedPlug(t)
+	rec := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hello", nil))
+
+	span := exportedSpan(t, exp)
+	if span.Name != "getGreeting" || span.StatusCode != http.StatusOK || span.Err != nil {
+		t.Errorf("span = %s %d %v, want getGreeting 200", span.Name, span.StatusCode, span.Err)
+	}
+	if span.Parent.IsValid() || !span.SpanContext.IsValid() || !span.SpanContext.Sampled {
+		t.Errorf("span context = %+v, parent = %+v, want a new sampled trace", span.SpanContext, span.Parent)
+	}
+	if got := rec.Header().Get(plugger.TraceParentHeader); got != span.SpanContext.TraceParent() {
+		t.Errorf("traceparent = %q, want %q", got, span.SpanContext.TraceParent())
+	}
+}
+
+func TestWithTracingContinuesTrace(t *testing.T) {
+	const (
+		traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"
+		traceState  = "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE"
+	)
+	parent, err := plugger.ParseTraceParent(traceParent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, exp := tracedPlug(t)
+	req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+	req.Header.Set(plugger.TraceParentHeader, traceParent)
+	req.Header.Set(plugger.TraceStateHeader, traceState)
+	rec := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rec, req)
+
+	span := exportedSpan(t, exp)
+	if span.Parent != parent {
+		t.Errorf("parent = %+v, want %+v", span.Parent, parent)
+	}
+	sc := span.SpanContext
+	if sc.TraceID != parent.TraceID || sc.SpanID == parent.SpanID || sc.Sampled {
+		t.Errorf("span context = %+v, want a not sampled child of %+v", sc, parent)
+	}
+	if sc.TraceState != traceState {
+		t.Errorf("tracestate = %q, want %q", sc.TraceState, traceState)
+	}
+	if got := rec.Header().Get(plugger.TraceParentHeader); got != sc.TraceParent() {
+		t.Errorf("response traceparent = %q, want %q", got, sc.TraceParent())
+	}
+	if got := rec.Header().Get(plugger.TraceStateHeader); got != traceState {
+		t.Errorf("response tracestate = %q, want %q", got, traceState)
+	}
+}
+
+func TestWithTracingRecordsErrors(t *testing.T) {
+	p, exp := tracedPlug(t)
+	// the handler is built again, but the error is recorded once
+	if err := p.Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/hello", nil))
+
+	span := exportedSpan(t, exp)
+	if span.StatusCode != http.StatusMethodNotAllowed || span.Err == nil {
+		t.Errorf("span = %d %v, want 405 with the API error", span.StatusCode, span.Err)
+	}
+}
+
+func TestWithTracingMountedAPI(t *testing.T) {
+	p, exp := tracedPlug(t)
+	if err := p.MountAPI("/v2", newGreetingAPI(t)); err != nil {
+		t.Fatal(err)
+	}
+	p.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v2/hello", nil))
+
+	span := exportedSpan(t, exp)
+	if span.StatusCode != http.StatusMethodNotAllowed || span.Err == nil {
+		t.Errorf("span = %d %v, want 405 with the mounted API error", span.StatusCode, span.Err)
+	}
+}
+
+func TestServeErrorWithoutTracing(t *testing.T) {
+	api := newGreetingAPI(t)
+	configureAPI(api)
+	serveError := reflect.ValueOf(api.ServeError).Pointer()
+	p, err := plugger.NewAPIPlug(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Handler()
+
+	if reflect.ValueOf(api.ServeError).Pointer() != serveError {
+		t.Error("ServeError is wrapped without tracing")
+	}
+}
+
+func TestParseTraceParent(t *testing.T) {
+	sc, err := plugger.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sc.Sampled || sc.TraceParent() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("parsed %+v", sc)
+	}
+	// future versions may have more fields
+	if _, err := plugger.ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); err != nil {
+		t.Errorf("future version: %v", err)
+	}
+
+	for _, s := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"0-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+	} {
+		if _, err := plugger.ParseTraceParent(s); err != plugger.ErrInvalidTraceParent {
+			t.Errorf("ParseTraceParent(%q) = %v, want %v", s, err, plugger.ErrInvalidTraceParent)
+		}
+	}
+}